@@ -2,23 +2,81 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"nyaa-crawler/internal/crawler"
 	"nyaa-crawler/internal/db"
+	"nyaa-crawler/internal/scheduler"
 )
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
 	dsn := flag.String("db", "", "PostgreSQL connection string (or use NYAA_DB env)")
-	scrapeURL := flag.String("url", "https://nyaa.si/", "URL to scrape data from")
-	proxyURL := flag.String("proxy", "", "Proxy URL (http/https/socks5, or use NYAA_PROXY env)")
+	siteName := flag.String("site", "nyaa", "Site to crawl: nyaa or sukebei")
+	scrapeURL := flag.String("url", "", "URL to scrape data from (default: the site's base URL)")
+	mirrorList := flag.String("mirrors", "", "Comma-separated fallback base URLs, tried in order when the site fails with connection errors or Cloudflare challenges")
+	proxyURL := flag.String("proxy", "", "Proxy URL (http/https/socks5/socks5h, or use NYAA_PROXY env)")
+	proxyList := flag.String("proxies", "", "Comma-separated proxy pool (http/https/socks5/socks5h URLs), used instead of -proxy")
+	proxyFile := flag.String("proxy-file", "", "File with one proxy URL per line, added to the -proxies pool")
+	proxyRotate := flag.String("proxy-rotate", "request", "When to switch proxies in the pool: request (round-robin) or failure")
+	proxyEvict := flag.Duration("proxy-evict", 5*time.Minute, "How long a failing proxy is left out of the pool")
+	pages := flag.Int("pages", 1, "Number of listing pages to crawl (follows ?p=N pagination)")
+	query := flag.String("q", "", "Search terms (Nyaa q parameter)")
+	category := flag.String("c", "", "Category code (Nyaa c parameter, e.g., 1_2 for English-translated anime)")
+	filter := flag.Int("f", 0, "Filter: 0 = none, 1 = no remakes, 2 = trusted only (Nyaa f parameter)")
+	sortField := flag.String("s", "", "Sort by: id, size, comments, seeders, leechers, downloads (Nyaa s parameter)")
+	order := flag.String("o", "", "Sort order: asc or desc (Nyaa o parameter)")
+	sourceName := flag.String("source", "html", "Listing source: html (scrape the table) or rss (read ?page=rss)")
+	viewID := flag.Int("view", 0, "Scrape the /view/ID detail page of a single torrent instead of listings")
+	backfill := flag.Bool("backfill", false, "Walk /view/ID pages downwards to backfill older torrents (resumable)")
+	backfillFrom := flag.Int("backfill-from", 0, "Highest ID to backfill (default: resume, or start at the highest stored ID)")
+	backfillTo := flag.Int("backfill-to", 0, "Lowest ID to backfill, inclusive (default: 1)")
+	backfillName := flag.String("backfill-name", "default", "Name under which backfill progress is stored")
+	backfillDelay := flag.Duration("backfill-delay", time.Second, "Pause between backfill requests")
+	reconcile := flag.Int("reconcile", 0, "Re-check this many stored torrents and mark removed ones as deleted")
+	reconcileDelay := flag.Duration("reconcile-delay", time.Second, "Pause between reconciliation requests")
+	concurrency := flag.Int("concurrency", 1, "Pages fetched in parallel by listing crawls, -backfill and -reconcile (all share -rate)")
+	rate := flag.Float64("rate", 1, "Maximum requests per second to each host (0 disables the limit)")
+	burst := flag.Int("burst", 2, "Requests allowed in a burst before -rate applies")
+	jitter := flag.Duration("jitter", 500*time.Millisecond, "Random extra pause of up to this long before each request")
+	retries := flag.Int("retries", 3, "Attempts per request; 404s are never retried")
+	retryMaxDelay := flag.Duration("retry-max-delay", time.Minute, "Longest backoff between attempts; a longer Retry-After gives up instead")
+	breakerThreshold := flag.Int("breaker-threshold", 5, "Consecutive failed requests that open the circuit breaker (0 disables it)")
+	breakerCooldown := flag.Duration("breaker-cooldown", 2*time.Minute, "How long the open circuit breaker fails fast before probing again")
+	cacheDir := flag.String("cache-dir", "", "Keep ETag/Last-Modified of listing pages here and skip pages that have not changed")
+	snapshotDir := flag.String("snapshot-dir", "", "Store a gzip copy of every parsed page in this directory")
+	reparse := flag.String("reparse", "", "Parse the snapshots in this directory again instead of crawling")
+	importPath := flag.String("import", "", "Import saved pages from this file or directory (HTML, .gz snapshots, WARC) instead of crawling")
+	importWorkers := flag.Int("import-workers", 4, "Files parsed in parallel by -import")
+	importBatch := flag.Int("import-batch", 500, "Torrents per database insert during -import")
+	layoutCheck := flag.Bool("layout-check", true, "Exit with an error when listing pages no longer match the expected table layout")
+	maxMalformed := flag.Float64("max-malformed", 0.2, "Fraction of listing rows allowed to fail parsing before the layout counts as changed")
+	daemon := flag.Bool("daemon", false, "Keep running and repeat the listing crawl on a schedule")
+	interval := flag.Duration("interval", 15*time.Minute, "Time between crawls in -daemon mode")
+	cronSpec := flag.String("cron", "", "Cron expression for -daemon mode, e.g. \"*/10 * * * *\" (overrides -interval)")
+	incremental := flag.Bool("incremental", false, "Stop crawling at the first page with only known torrents (-pages is the upper bound)")
 	flag.Parse()
 
+	// A changed listing layout exits non-zero. Deferred calls run last in,
+	// first out, so registering this one first lets the cleanup below run
+	// before it.
+	var layoutErr error
+	defer func() {
+		if layoutErr != nil {
+			log.Fatalf("Listing layout changed, the parser needs updating: %v", layoutErr)
+		}
+	}()
+
 	// DSN priority: CLI flag > NYAA_DB env > default
 	dsnValue := *dsn
 	if dsnValue == "" {
@@ -34,8 +92,56 @@ func main() {
 		proxy = os.Getenv("NYAA_PROXY")
 	}
 
+	site, err := crawler.SiteByName(*siteName)
+	if err != nil {
+		log.Fatal("Invalid site:", err)
+	}
+	baseURL := *scrapeURL
+	if baseURL == "" {
+		baseURL = site.BaseURL
+	}
+	if *category != "" && !site.HasCategory(*category) {
+		log.Fatalf("Invalid category %q for site %s", *category, site.Name)
+	}
+
+	search := crawler.SearchQuery{
+		Query:    *query,
+		Category: *category,
+		Filter:   *filter,
+		Sort:     *sortField,
+		Order:    *order,
+	}
+	targetURL, err := search.BuildURL(baseURL)
+	if err != nil {
+		log.Fatal("Invalid search parameters:", err)
+	}
+	if *incremental && !search.IsNewestFirst() {
+		log.Fatal("Incremental crawling requires results sorted by ID, newest first")
+	}
+
+	var schedule scheduler.Schedule
+	if *daemon {
+		if *viewID > 0 || *reconcile > 0 || *backfill || *importPath != "" || *reparse != "" {
+			log.Fatal("-daemon only repeats listing crawls and cannot be combined with -view, -reconcile, -backfill, -import or -reparse")
+		}
+		if *cronSpec != "" {
+			schedule, err = scheduler.Parse(*cronSpec)
+		} else {
+			schedule, err = scheduler.Every(*interval)
+		}
+		if err != nil {
+			log.Fatal("Invalid schedule:", err)
+		}
+	}
+
 	log.Printf("Database: %s", sanitizeDSN(dsnValue))
-	log.Printf("Scraping URL: %s", *scrapeURL)
+	log.Printf("Site: %s", site.Name)
+	log.Printf("Scraping URL: %s", targetURL)
+
+	source, err := crawler.ParseSource(*sourceName)
+	if err != nil {
+		log.Fatal("Invalid source:", err)
+	}
 
 	// Create database service
 	dbs, err := db.NewDBService(dsnValue)
@@ -49,19 +155,116 @@ func main() {
 		log.Fatal("Failed to run database migrations:", err)
 	}
 
-	// Create crawler with dependency injection
-	c, err := crawler.NewCrawler(
+	retryPolicy := crawler.DefaultRetryPolicy()
+	retryPolicy.MaxDelay = *retryMaxDelay
+
+	// -url may point below the site root, such as a user's listing, while the
+	// mirrors and /view/ID pages are relative to the root
+	root, err := siteRoot(baseURL)
+	if err != nil {
+		log.Fatal("Invalid URL:", err)
+	}
+	mirrors := append([]string{root}, splitList(*mirrorList)...)
+
+	proxyOption := crawler.WithProxy(proxy)
+	proxies := splitList(*proxyList)
+	if *proxyFile != "" {
+		fromFile, err := crawler.LoadProxyList(*proxyFile)
+		if err != nil {
+			log.Fatal("Failed to read proxy file:", err)
+		}
+		proxies = append(proxies, fromFile...)
+	}
+	if len(proxies) > 0 {
+		rotation, err := crawler.ParseProxyRotation(*proxyRotate)
+		if err != nil {
+			log.Fatal("Invalid proxy rotation:", err)
+		}
+		proxyOption = crawler.WithProxyPool(proxies, crawler.ProxyPoolOptions{Rotation: rotation, EvictFor: *proxyEvict})
+	}
+
+	opts := []crawler.Option{
 		crawler.WithDB(dbs),
-		crawler.WithProxy(proxy),
-	)
+		proxyOption,
+		crawler.WithSource(source),
+		crawler.WithSite(site),
+		crawler.WithBaseURL(root),
+		crawler.WithMirrors(mirrors...),
+		crawler.WithRateLimit(*rate, *burst),
+		crawler.WithConcurrency(*concurrency),
+		crawler.WithJitter(*jitter),
+		crawler.WithMaxRetries(*retries),
+		crawler.WithRetryPolicy(retryPolicy),
+		crawler.WithCircuitBreaker(*breakerThreshold, *breakerCooldown),
+	}
+	if *cacheDir != "" || *daemon {
+		// The daemon keeps validators in memory between runs at least
+		opts = append(opts, crawler.WithHTTPCache(*cacheDir))
+	}
+	if *layoutCheck {
+		opts = append(opts, crawler.WithLayoutCheck(*maxMalformed))
+	}
+	if *snapshotDir != "" {
+		opts = append(opts, crawler.WithSnapshotDir(*snapshotDir))
+	}
+
+	// Create crawler with dependency injection
+	c, err := crawler.NewCrawler(opts...)
 	if err != nil {
 		log.Fatal("Failed to create crawler:", err)
 	}
 
-	log.Printf("Starting to scrape from web: %s", *scrapeURL)
+	defer logProxyStats(c)
+
+	// SIGINT/SIGTERM cancel the context so runs stop cleanly and save progress
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	ctx := context.Background()
-	if err := c.ScrapePage(ctx, *scrapeURL); err != nil {
+	if *importPath != "" {
+		runImport(ctx, c, *importPath, crawler.ImportOptions{Workers: *importWorkers, BatchSize: *importBatch})
+		return
+	}
+	if *reparse != "" {
+		runReparse(ctx, c, *reparse)
+		return
+	}
+	if *viewID > 0 {
+		scrapeDetail(ctx, c, *viewID)
+		return
+	}
+	if *reconcile > 0 {
+		runReconcile(ctx, c, crawler.ReconcileOptions{Limit: *reconcile, Delay: *reconcileDelay})
+		return
+	}
+	if *backfill {
+		runBackfill(ctx, c, crawler.BackfillOptions{
+			Name:   *backfillName,
+			FromID: *backfillFrom,
+			ToID:   *backfillTo,
+			Delay:  *backfillDelay,
+		})
+		return
+	}
+
+	if *daemon {
+		layoutErr = runDaemon(ctx, c, schedule, func(ctx context.Context) error {
+			return crawlListings(ctx, c, targetURL, *pages, *incremental)
+		})
+		return
+	}
+
+	log.Printf("Starting to scrape from web: %s", targetURL)
+
+	err = crawlListings(ctx, c, targetURL, *pages, *incremental)
+	if errors.Is(err, crawler.ErrCircuitOpen) {
+		log.Printf("Upstream unavailable, skipping the rest of this run: %v", err)
+		return
+	}
+	if errors.Is(err, crawler.ErrLayoutChanged) {
+		layoutErr = err
+		return
+	}
+	if err != nil {
 		log.Printf("Error scraping: %v", err)
 		log.Println("Failed to scrape. Exiting.")
 		return
@@ -85,6 +288,168 @@ func main() {
 	}
 }
 
+// crawlListings runs one listing crawl and logs its per-page summary
+func crawlListings(ctx context.Context, c *crawler.Crawler, targetURL string, pages int, incremental bool) error {
+	var results []crawler.PageResult
+	var err error
+	if incremental {
+		results, err = c.ScrapeIncremental(ctx, targetURL, pages)
+	} else {
+		results, err = c.ScrapePages(ctx, targetURL, pages)
+	}
+	logPageResults(results)
+	return err
+}
+
+// runDaemon repeats crawl on schedule until ctx is cancelled, reusing the
+// crawler and its database connections. Runs are skipped while the circuit
+// breaker is open. A changed listing layout stops the daemon and is returned
+// once the scheduler has stopped.
+func runDaemon(ctx context.Context, c *crawler.Crawler, schedule scheduler.Schedule, crawl scheduler.Job) error {
+	log.Printf("Daemon mode, schedule: %v", schedule)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Runs never overlap and Run waits for the last one, so layoutErr is
+	// only read after every write to it
+	var layoutErr error
+	s := scheduler.New(schedule, func(ctx context.Context) error {
+		if c.BreakerState() == crawler.BreakerOpen {
+			log.Println("Circuit breaker is open, skipping this run")
+			return nil
+		}
+		err := crawl(ctx)
+		if errors.Is(err, crawler.ErrCircuitOpen) {
+			log.Printf("Upstream unavailable, skipping the rest of this run: %v", err)
+			return nil
+		}
+		if errors.Is(err, crawler.ErrLayoutChanged) {
+			layoutErr = err
+			cancel()
+		}
+		return err
+	}, scheduler.RunImmediately())
+	s.Run(ctx)
+
+	runs, skipped := s.Stats()
+	log.Printf("Daemon stopped after %d runs (%d skipped while a run was in progress)", runs, skipped)
+	return layoutErr
+}
+
+// scrapeDetail scrapes and logs the detail page of a single torrent
+func scrapeDetail(ctx context.Context, c *crawler.Crawler, id int) {
+	log.Printf("Scraping detail page of torrent %d", id)
+	detail, err := c.ScrapeDetail(ctx, id)
+	if err != nil {
+		log.Printf("Error scraping detail page: %v", err)
+		return
+	}
+	log.Printf("Torrent %d: %s (%s), submitted by %s, %d files, %d comments",
+		detail.Torrent.ID, detail.Torrent.Name, detail.Torrent.Size, detail.Submitter, len(detail.Files), detail.CommentCount)
+}
+
+// runBackfill runs a resumable backfill and logs its summary
+func runBackfill(ctx context.Context, c *crawler.Crawler, opts crawler.BackfillOptions) {
+	result, err := c.Backfill(ctx, opts)
+	if result != nil {
+		log.Printf("Backfill visited %d IDs: %d stored, %d missing, next ID %d",
+			result.Visited, result.Stored, result.Missing, result.NextID)
+	}
+	if err != nil {
+		log.Printf("Error backfilling: %v", err)
+	}
+}
+
+// runReconcile re-checks stored torrents for removal and logs its summary
+func runReconcile(ctx context.Context, c *crawler.Crawler, opts crawler.ReconcileOptions) {
+	result, err := c.Reconcile(ctx, opts)
+	if result != nil {
+		log.Printf("Reconciled %d torrents: %d removed", result.Checked, result.Deleted)
+	}
+	if err != nil {
+		log.Printf("Error reconciling: %v", err)
+	}
+}
+
+// runReparse parses stored snapshots again and logs its summary
+func runReparse(ctx context.Context, c *crawler.Crawler, dir string) {
+	log.Printf("Reparsing snapshots in %s", dir)
+	result, err := c.Reparse(ctx, dir)
+	if result != nil {
+		log.Printf("Reparsed %d snapshots (%d skipped, %d failed): %d torrents found, %d inserted",
+			result.Files, result.Skipped, result.Failed, result.Found, result.Inserted)
+	}
+	if err != nil {
+		log.Printf("Error reparsing: %v", err)
+	}
+}
+
+// runImport imports saved pages and logs its summary
+func runImport(ctx context.Context, c *crawler.Crawler, path string, opts crawler.ImportOptions) {
+	log.Printf("Importing saved pages from %s", path)
+	result, err := c.Import(ctx, path, opts)
+	if result != nil {
+		log.Printf("Imported %d files (%d pages, %d skipped, %d failed): %d torrents found, %d inserted",
+			result.Files, result.Pages, result.Skipped, result.Failed, result.Found, result.Inserted)
+	}
+	if err != nil {
+		log.Printf("Error importing: %v", err)
+	}
+}
+
+// logPageResults logs the per-page insert results of a crawl and their totals
+func logPageResults(results []crawler.PageResult) {
+	var found, inserted int
+	for _, r := range results {
+		if r.NotModified {
+			log.Printf("Page %d: not modified (%s)", r.Page, r.URL)
+			continue
+		}
+		log.Printf("Page %d: found %d, inserted %d (%s via %s)", r.Page, r.Found, r.Inserted, r.URL, r.Mirror)
+		if r.Malformed > 0 {
+			log.Printf("Page %d: %d rows failed to parse", r.Page, r.Malformed)
+		}
+		found += r.Found
+		inserted += r.Inserted
+	}
+	log.Printf("Crawled %d pages: found %d torrents, inserted %d new", len(results), found, inserted)
+}
+
+// logProxyStats logs the success rate of each proxy in the pool, if any
+func logProxyStats(c *crawler.Crawler) {
+	for _, s := range c.ProxyStats() {
+		state := "active"
+		if s.Evicted {
+			state = "evicted"
+		}
+		log.Printf("Proxy %s: %d ok, %d failed (%.0f%% success, %s)",
+			s.Proxy, s.Successes, s.Failures, s.SuccessRate()*100, state)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// siteRoot returns the scheme and host of a site URL, dropping its path and query
+func siteRoot(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("URL must be absolute: %q", raw)
+	}
+	return (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host, Path: "/"}).String(), nil
+}
+
 // sanitizeDSN masks password in database connection string for safe logging
 func sanitizeDSN(dsn string) string {
 	u, err := url.Parse(dsn)
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 	"unicode/utf8"
 
+	"nyaa-crawler/internal/crawler"
 	"nyaa-crawler/internal/db"
 	"nyaa-crawler/internal/downloader"
+	"nyaa-crawler/pkg/magnet"
 	"nyaa-crawler/pkg/models"
 )
 
@@ -19,14 +24,56 @@ func main() {
 
 	// Define command line flags
 	dsn := flag.String("db", "", "PostgreSQL connection string (or use NYAA_DB env)")
+	site := flag.String("site", "", "Only show torrents from this site: nyaa, sukebei (default all; nyaa for -trend and -detail)")
 	searchPattern := flag.String("regex", "", "Text pattern to match in torrent names (using LIKE operator)")
 	limit := flag.Int("limit", 10, "Number of results to show")
+	sortBy := flag.String("sort", "id", "Sort results by: id, seeders, leechers, completed, size, date")
+	minSeeders := flag.Int("min-seeders", 0, "Only show torrents with at least this many seeders")
+	minSize := flag.String("min-size", "", "Only show torrents at least this large (e.g., 500MiB)")
+	maxSize := flag.String("max-size", "", "Only show torrents at most this large (e.g., 4GiB)")
+	after := flag.String("after", "", "Only show torrents uploaded at or after this UTC date (YYYY-MM-DD or \"YYYY-MM-DD HH:MM\")")
+	before := flag.String("before", "", "Only show torrents uploaded before this UTC date (YYYY-MM-DD or \"YYYY-MM-DD HH:MM\")")
+	showDeleted := flag.Bool("show-deleted", false, "Include torrents that were removed from Nyaa (flagged as deleted)")
+	trendID := flag.Int("trend", 0, "Show the seeder/leecher history of the torrent with this ID")
+	detailID := flag.Int("detail", 0, "Show the stored detail page data and file list of the torrent with this ID")
+	hash := flag.String("hash", "", "Show the stored torrent with this info hash (hex, base32 or a magnet link)")
+	growing := flag.Duration("growing", 0, "Rank the fastest-growing torrents over this window (e.g., 24h)")
 	transmissionURL := flag.String("transmission", "", "Transmission RPC URL (e.g., user:pass@http://localhost:9091/transmission/rpc)")
 	aria2URL := flag.String("aria2", "", "aria2 RPC URL (e.g., token@http://localhost:6800/jsonrpc)")
 	downloadDir := flag.String("download-dir", "", "Download directory for Transmission and aria2 (e.g., /path/to/downloads)")
 	dryRun := flag.Bool("dry-run", false, "Show what would be sent to Transmission/aria2 without actually sending")
+	trackers := flag.String("trackers", "", "Comma-separated tracker URLs to add to magnet links before sending them to Transmission/aria2")
+	trackersFile := flag.String("trackers-file", "", "File of tracker URLs to add to magnet links, one per line (# starts a comment)")
+	stripTrackers := flag.String("strip-trackers", "", "Comma-separated dead trackers to remove from magnet links, as announce URLs or host names")
+	dedupeTrackers := flag.Bool("dedupe-trackers", true, "Drop duplicate trackers when rewriting magnet links")
 	flag.Parse()
 
+	filter := models.TorrentFilter{
+		Site:           *site,
+		Pattern:        *searchPattern,
+		MinSeeders:     *minSeeders,
+		SortBy:         models.SortField(*sortBy),
+		IncludeDeleted: *showDeleted,
+		Limit:          *limit,
+	}
+	var err error
+	if filter.MinSize, err = parseSizeFlag(*minSize); err != nil {
+		log.Fatal("Invalid -min-size:", err)
+	}
+	if filter.MaxSize, err = parseSizeFlag(*maxSize); err != nil {
+		log.Fatal("Invalid -max-size:", err)
+	}
+	if filter.PublishedAfter, err = parseDateFlag(*after); err != nil {
+		log.Fatal("Invalid -after:", err)
+	}
+	if filter.PublishedBefore, err = parseDateFlag(*before); err != nil {
+		log.Fatal("Invalid -before:", err)
+	}
+	rewriter, err := trackerRewriter(*trackers, *trackersFile, *stripTrackers, *dedupeTrackers)
+	if err != nil {
+		log.Fatal("Invalid tracker list:", err)
+	}
+
 	// DSN priority: CLI flag > NYAA_DB env > default
 	dsnValue := *dsn
 	if dsnValue == "" {
@@ -42,27 +89,78 @@ func main() {
 	}
 	defer dbs.Close()
 
-	var torrents []models.Torrent
+	if *trendID > 0 {
+		printTrend(dbs, *site, *trendID, *limit)
+		return
+	}
+	if *detailID > 0 {
+		printDetail(dbs, *site, *detailID)
+		return
+	}
+	if *hash != "" {
+		printByInfoHash(dbs, *hash)
+		return
+	}
 
-	if *searchPattern != "" {
-		torrents, err = dbs.GetTorrentsByPattern(*searchPattern, *limit)
+	var torrents []models.Torrent
+	if *growing > 0 {
+		growth, err := dbs.GetFastestGrowing(*growing, *limit)
 		if err != nil {
 			log.Fatal("Failed to query database:", err)
 		}
-		fmt.Printf("Torrents matching pattern '%s' (limit %d):\n", *searchPattern, *limit)
+		fmt.Printf("Fastest-growing torrents over the last %s (limit %d):\n", *growing, *limit)
+		printGrowth(growth)
+		for _, g := range growth {
+			torrents = append(torrents, g.Torrent)
+		}
 	} else {
-		torrents, err = dbs.GetLatestTorrents(*limit)
-		if err != nil {
-			log.Fatal("Failed to query database:", err)
+		torrents = queryTorrents(dbs, filter)
+	}
+
+	// Process magnet links for Transmission and aria2
+	if *transmissionURL != "" || *aria2URL != "" {
+		if *dryRun {
+			showDryRunInfo(torrents, *transmissionURL, *aria2URL, *downloadDir, rewriter)
+		} else {
+			processDownloads(dbs, torrents, *transmissionURL, *aria2URL, *downloadDir, rewriter)
 		}
-		fmt.Printf("Latest %d torrents:\n", *limit)
 	}
+}
+
+// queryTorrents lists torrents matching the filter flags along with match statistics
+func queryTorrents(reader models.TorrentReader, filter models.TorrentFilter) []models.Torrent {
+	torrents, err := reader.FindTorrents(filter)
+	if err != nil {
+		log.Fatal("Failed to query database:", err)
+	}
+
+	if filter.Pattern != "" {
+		fmt.Printf("Torrents matching pattern '%s' (limit %d)", filter.Pattern, filter.Limit)
+	} else {
+		fmt.Printf("Latest %d torrents", filter.Limit)
+	}
+	if filter.SortBy != models.SortByID {
+		fmt.Printf(", sorted by %s", filter.SortBy)
+	}
+	if filter.MinSeeders > 0 {
+		fmt.Printf(", with at least %d seeders", filter.MinSeeders)
+	}
+	if filter.MinSize > 0 || filter.MaxSize > 0 {
+		fmt.Printf(", sized %s to %s", sizeBound(filter.MinSize), sizeBound(filter.MaxSize))
+	}
+	if !filter.PublishedAfter.IsZero() {
+		fmt.Printf(", uploaded since %s", filter.PublishedAfter.Format("2006-01-02 15:04"))
+	}
+	if !filter.PublishedBefore.IsZero() {
+		fmt.Printf(", uploaded before %s", filter.PublishedBefore.Format("2006-01-02 15:04"))
+	}
+	fmt.Println(":")
 
 	printTorrents(torrents)
 
 	// Show matching count if using search pattern
-	if *searchPattern != "" {
-		matchCount, err := dbs.GetMatchCount(*searchPattern)
+	if filter.Pattern != "" {
+		matchCount, err := reader.GetMatchCount(filter.Pattern)
 		if err != nil {
 			log.Printf("Warning: Failed to get match count: %v", err)
 		} else {
@@ -71,7 +169,7 @@ func main() {
 	}
 
 	// Show some statistics
-	total, withMagnet, err := dbs.GetTorrentCount()
+	total, withMagnet, err := reader.GetTorrentCount()
 	if err != nil {
 		log.Printf("Warning: Failed to get statistics: %v", err)
 	} else {
@@ -79,20 +177,132 @@ func main() {
 		fmt.Printf("Torrents with magnet links: %d\n", withMagnet)
 	}
 
-	// Process magnet links for Transmission and aria2
-	if *transmissionURL != "" || *aria2URL != "" {
-		if *dryRun {
-			showDryRunInfo(torrents, *transmissionURL, *aria2URL, *downloadDir)
-		} else {
-			processDownloads(dbs, torrents, *transmissionURL, *aria2URL, *downloadDir)
+	return torrents
+}
+
+// parseSizeFlag parses a size flag such as "500MiB", returning 0 if it is empty
+func parseSizeFlag(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return crawler.ParseSize(value)
+}
+
+// parseDateFlag parses a UTC date flag in the format shown by Nyaa, with or
+// without the time, returning the zero time if it is empty
+func parseDateFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD or YYYY-MM-DD HH:MM date", value)
+}
+
+// sizeBound describes one end of a size range in bytes
+func sizeBound(n int64) string {
+	if n <= 0 {
+		return "any"
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// printTrend prints the recorded swarm history of a torrent, newest first
+func printTrend(reader models.TorrentStatsReader, site string, id, limit int) {
+	stats, err := reader.GetTorrentStats(site, id, limit)
+	if err != nil {
+		log.Fatal("Failed to query database:", err)
+	}
+
+	fmt.Printf("Swarm history for torrent %d (limit %d):\n", id, limit)
+	fmt.Printf("%-20s %-10s %-10s %-10s\n", "Recorded At", "Seeders", "Leechers", "Done")
+	fmt.Println(strings.Repeat("-", 53))
+	for _, st := range stats {
+		fmt.Printf("%-20s %-10d %-10d %-10d\n",
+			st.RecordedAt.Local().Format("2006-01-02 15:04:05"), st.Seeders, st.Leechers, st.Completed)
+	}
+	if len(stats) == 0 {
+		fmt.Println("No history recorded")
+	}
+}
+
+// printDetail prints the stored detail page data of a torrent
+func printDetail(reader models.TorrentDetailReader, site string, id int) {
+	detail, err := reader.GetTorrentDetail(site, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Printf("No detail page stored for torrent %d (scrape it with nyaa-crawler -view %d)\n", id, id)
+		return
+	}
+	if err != nil {
+		log.Fatal("Failed to query database:", err)
+	}
+
+	t := detail.Torrent
+	fmt.Printf("Site:        %s\n", t.Site)
+	fmt.Printf("ID:          %d\n", t.ID)
+	fmt.Printf("Name:        %s\n", t.Name)
+	fmt.Printf("Category:    %s\n", t.Category)
+	fmt.Printf("Size:        %s\n", t.Size)
+	fmt.Printf("Date:        %s\n", t.Date)
+	fmt.Printf("Submitter:   %s\n", detail.Submitter)
+	fmt.Printf("Information: %s\n", detail.InformationURL)
+	fmt.Printf("Info hash:   %s\n", t.InfoHash)
+	fmt.Printf("Trusted:     %v\n", t.Trusted)
+	fmt.Printf("Remake:      %v\n", t.Remake)
+	fmt.Printf("Comments:    %d\n", detail.CommentCount)
+	fmt.Printf("\nFiles (%d):\n", len(detail.Files))
+	for _, f := range detail.Files {
+		fmt.Printf("  %-12s %s\n", f.Size, f.Path)
+	}
+	fmt.Printf("\nDescription:\n%s\n", detail.Description)
+}
+
+// printByInfoHash prints the stored torrent matching an info hash or the
+// info hash of a magnet link
+func printByInfoHash(reader models.TorrentReader, value string) {
+	var infoHash string
+	var err error
+	if strings.HasPrefix(value, "magnet:") {
+		var m *magnet.Magnet
+		if m, err = magnet.Parse(value); err == nil {
+			infoHash = m.InfoHash
 		}
+	} else {
+		infoHash, err = magnet.NormalizeInfoHash(value)
+	}
+	if err != nil {
+		log.Fatal("Invalid info hash:", err)
+	}
+
+	t, err := reader.GetTorrentByInfoHash(infoHash)
+	if err != nil {
+		log.Fatal("Failed to query database:", err)
+	}
+	if t == nil {
+		fmt.Printf("No torrent stored with info hash %s\n", infoHash)
+		return
+	}
+	printTorrents([]models.Torrent{*t})
+}
+
+// printGrowth prints torrents ranked by swarm growth
+func printGrowth(growth []models.TorrentGrowth) {
+	fmt.Printf("%-10s %-50s %-10s %-10s %-12s %-12s\n", "ID", "Name", "Seeders", "Done", "Seeders +/-", "Done +/-")
+	fmt.Println(strings.Repeat("-", 109))
+	for _, g := range growth {
+		fmt.Printf("%-10d %-50s %-10d %-10d %-+12d %-+12d\n",
+			g.ID, truncateRunes(g.Name, 49), g.Seeders, g.Completed, g.SeedersDelta, g.CompletedDelta)
 	}
 }
 
 // printTorrents prints the torrents in a formatted table
 func printTorrents(torrents []models.Torrent) {
-	fmt.Printf("%-10s %-50s %-25s %-10s %-10s %-12s %-12s\n", "ID", "Name", "Category", "Size", "Date", "To Trans", "To Aria2")
-	fmt.Println(strings.Repeat("-", 135))
+	fmt.Printf("%-8s %-10s %-50s %-25s %-10s %-10s %-8s %-8s %-8s %-12s %-12s\n",
+		"Site", "ID", "Name", "Category", "Size", "Date", "Seeders", "Leechers", "Done", "To Trans", "To Aria2")
+	fmt.Println(strings.Repeat("-", 171))
 
 	for _, t := range torrents {
 		transStatus := "No"
@@ -104,13 +314,67 @@ func printTorrents(torrents []models.Torrent) {
 			aria2Status = "Yes"
 		}
 
-		fmt.Printf("%-10d %-50s %-25s %-10s %-10s %-12s %-12s\n",
-			t.ID, truncateRunes(t.Name, 49), t.Category, t.Size, t.Date, transStatus, aria2Status)
+		name := t.Name
+		if t.DeletedAt != nil {
+			name = "[deleted] " + name
+		}
+
+		fmt.Printf("%-8s %-10d %-50s %-25s %-10s %-10s %-8d %-8d %-8d %-12s %-12s\n",
+			t.Site, t.ID, truncateRunes(name, 49), t.Category, t.Size, t.Date,
+			t.Seeders, t.Leechers, t.Completed, transStatus, aria2Status)
+	}
+}
+
+// trackerRewriter builds the tracker rewriting applied to magnet links before
+// they are sent, merging the -trackers list with the -trackers-file list. It
+// returns nil if no trackers are added or stripped and duplicates are kept.
+func trackerRewriter(list, file, strip string, dedupe bool) (*magnet.Rewriter, error) {
+	rewriter := &magnet.Rewriter{
+		Add:    splitList(list),
+		Strip:  splitList(strip),
+		Dedupe: dedupe,
+	}
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = f.Close() }()
+
+		fromFile, err := magnet.ReadTrackers(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		rewriter.Add = append(rewriter.Add, fromFile...)
+	}
+	if len(rewriter.Add) == 0 && len(rewriter.Strip) == 0 && !rewriter.Dedupe {
+		return nil, nil
+	}
+	return rewriter, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// withTrackers wraps dl so magnet links are rewritten before they are sent,
+// or returns dl unchanged if rewriter is nil
+func withTrackers(dl downloader.Downloader, rewriter *magnet.Rewriter) downloader.Downloader {
+	if rewriter == nil {
+		return dl
 	}
+	return downloader.NewTrackerRewriter(dl, *rewriter)
 }
 
 // processDownloads handles sending magnet links to download clients
-func processDownloads(updater models.TorrentStatusUpdater, torrents []models.Torrent, transmissionURL, aria2URL, downloadDir string) {
+func processDownloads(updater models.TorrentStatusUpdater, torrents []models.Torrent, transmissionURL, aria2URL, downloadDir string, rewriter *magnet.Rewriter) {
 	httpClient := &http.Client{}
 
 	if transmissionURL != "" {
@@ -121,7 +385,7 @@ func processDownloads(updater models.TorrentStatusUpdater, torrents []models.Tor
 			Password:    pass,
 			DownloadDir: downloadDir,
 		})
-		result := pushMagnetLinks(client, updater, torrents, models.PushTargetTransmission, func(t models.Torrent) bool {
+		result := pushMagnetLinks(withTrackers(client, rewriter), updater, torrents, models.PushTargetTransmission, func(t models.Torrent) bool {
 			return t.Magnet != "" && !t.PushedToTransmission
 		})
 		fmt.Printf("Sent %d magnet links to Transmission\n", result.Sent)
@@ -134,7 +398,7 @@ func processDownloads(updater models.TorrentStatusUpdater, torrents []models.Tor
 			Token:       token,
 			DownloadDir: downloadDir,
 		})
-		result := pushMagnetLinks(client, updater, torrents, models.PushTargetAria2, func(t models.Torrent) bool {
+		result := pushMagnetLinks(withTrackers(client, rewriter), updater, torrents, models.PushTargetAria2, func(t models.Torrent) bool {
 			return t.Magnet != "" && !t.PushedToAria2
 		})
 		fmt.Printf("Sent %d magnet links to aria2\n", result.Sent)
@@ -146,17 +410,22 @@ type PushResult struct {
 	Sent int
 }
 
-// pushMagnetLinks sends eligible magnet links to a downloader and updates their status
+// pushMagnetLinks sends eligible magnet links to a downloader and updates their status.
+// Torrents removed from Nyaa are never sent.
 func pushMagnetLinks(dl downloader.Downloader, updater models.TorrentStatusUpdater, torrents []models.Torrent, target models.PushTarget, shouldPush func(models.Torrent) bool) *PushResult {
 	result := &PushResult{}
 	for _, t := range torrents {
+		if t.DeletedAt != nil {
+			fmt.Printf("Refusing to send deleted torrent %d: %s\n", t.ID, truncateRunes(t.Name, 50))
+			continue
+		}
 		if shouldPush(t) {
 			fmt.Printf("Sending to %s: %s\n", target, truncateRunes(t.Name, 50))
 			if err := dl.AddMagnet(t.Magnet); err != nil {
 				fmt.Printf("  Failed: %v\n", err)
 				continue
 			}
-			if err := updater.UpdatePushedStatus(t.ID, target); err != nil {
+			if err := updater.UpdatePushedStatus(t.Site, t.ID, target); err != nil {
 				log.Printf("Failed to update status for id %d: %v", t.ID, err)
 			}
 			result.Sent++
@@ -166,11 +435,11 @@ func pushMagnetLinks(dl downloader.Downloader, updater models.TorrentStatusUpdat
 }
 
 // showDryRunInfo shows what would be sent without actually sending
-func showDryRunInfo(torrents []models.Torrent, transmissionURL, aria2URL, downloadDir string) {
+func showDryRunInfo(torrents []models.Torrent, transmissionURL, aria2URL, downloadDir string, rewriter *magnet.Rewriter) {
 	var transmissionCount, aria2Count int
 
 	for _, t := range torrents {
-		if t.Magnet != "" {
+		if t.Magnet != "" && t.DeletedAt == nil {
 			if transmissionURL != "" && !t.PushedToTransmission {
 				transmissionCount++
 			}
@@ -195,6 +464,13 @@ func showDryRunInfo(torrents []models.Torrent, transmissionURL, aria2URL, downlo
 		}
 		fmt.Println()
 	}
+
+	if rewriter != nil && transmissionCount+aria2Count > 0 {
+		fmt.Printf("Magnet links would get %d added trackers, with %d stripped trackers removed\n", len(rewriter.Add), len(rewriter.Strip))
+		if rewriter.Dedupe {
+			fmt.Println("Duplicate trackers would be dropped")
+		}
+	}
 }
 
 // truncateRunes truncates a string to at most maxRunes runes, preserving UTF-8 boundaries
@@ -0,0 +1,187 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nyaa-crawler/pkg/models"
+)
+
+// defaultBackfillName is the progress key used when BackfillOptions.Name is empty
+const defaultBackfillName = "default"
+
+// backfillStore is implemented by database services that persist backfill progress
+type backfillStore interface {
+	GetBackfillProgress(site, name string) (*models.BackfillProgress, error)
+	SaveBackfillProgress(progress models.BackfillProgress) error
+	MarkTorrentMissing(site string, id int, statusCode int) error
+}
+
+// BackfillOptions configures a backfill run over a range of torrent IDs
+type BackfillOptions struct {
+	// Name keys the stored progress, together with the crawler's site,
+	// so separate jobs resume independently
+	Name string
+	// FromID is the highest ID to visit. Zero resumes the stored job or
+	// starts from the highest torrent ID already in the database; a finished
+	// job is continued from that ID down to where it started.
+	FromID int
+	// ToID is the lowest ID to visit, inclusive. Zero means 1 for new jobs.
+	ToID int
+	// Delay pauses each worker between detail page requests
+	Delay time.Duration
+}
+
+// BackfillResult summarizes a backfill run
+type BackfillResult struct {
+	Visited int
+	Stored  int
+	Missing int
+	NextID  int
+}
+
+// Backfill walks /view/{id} pages downwards over an ID range, storing each
+// torrent and recording 404s as missing IDs. Progress is saved after every ID,
+// so an interrupted run resumes where it stopped when started with the same options.
+func (c *Crawler) Backfill(ctx context.Context, opts BackfillOptions) (*BackfillResult, error) {
+	store, ok := c.dbs.(backfillStore)
+	if !ok {
+		return nil, fmt.Errorf("database service does not support backfill progress")
+	}
+
+	progress, err := c.backfillProgress(store, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BackfillResult{NextID: progress.NextID}
+	if progress.NextID < progress.EndID {
+		log.Printf("Backfill %q already complete (%d..%d)", progress.Name, progress.StartID, progress.EndID)
+		return result, nil
+	}
+	log.Printf("Backfill %q: visiting IDs %d down to %d", progress.Name, progress.NextID, progress.EndID)
+
+	// Pages are fetched ahead by the worker pool, but stored and recorded in
+	// ID order, so the saved progress never skips an unvisited ID
+	start, end := progress.NextID, progress.EndID
+	fetchID := func(ctx context.Context, i int) (*models.TorrentDetail, error) {
+		id := start - i
+		detail, err := c.fetchDetail(ctx, id)
+		if opts.Delay > 0 && id > end {
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.Delay):
+			}
+		}
+		return detail, err
+	}
+	storeID := func(i int, detail *models.TorrentDetail, err error) (bool, error) {
+		id := start - i
+		var statusErr *StatusError
+		switch {
+		case err == nil:
+			if _, err := c.storeRows([]models.Torrent{detail.Torrent}, []models.TorrentDetail{*detail}); err != nil {
+				return false, fmt.Errorf("torrent %d: %w", id, err)
+			}
+			result.Stored++
+		case errors.Is(err, ErrNotFound) && errors.As(err, &statusErr):
+			if err := store.MarkTorrentMissing(c.site.Name, id, statusErr.StatusCode); err != nil {
+				return false, fmt.Errorf("failed to record missing torrent %d: %w", id, err)
+			}
+			// A stored torrent that now returns 404 has been removed from the site
+			if reconciler, ok := c.dbs.(torrentReconciler); ok {
+				if err := reconciler.MarkTorrentDeleted(c.site.Name, id); err != nil {
+					return false, fmt.Errorf("failed to mark torrent %d deleted: %w", id, err)
+				}
+			}
+			result.Missing++
+		default:
+			// Progress still points at this ID, so the next run retries it
+			return false, fmt.Errorf("torrent %d: %w", id, err)
+		}
+		result.Visited++
+
+		progress.NextID = id - 1
+		result.NextID = progress.NextID
+		if err := store.SaveBackfillProgress(*progress); err != nil {
+			return false, fmt.Errorf("failed to save backfill progress: %w", err)
+		}
+		return true, nil
+	}
+	if err := runOrdered(ctx, c.concurrency, start-end+1, fetchID, storeID, nil); err != nil {
+		return result, err
+	}
+
+	log.Printf("Backfill %q complete: %d stored, %d missing", progress.Name, result.Stored, result.Missing)
+	return result, nil
+}
+
+// backfillProgress resumes a stored job matching opts or starts a new one
+func (c *Crawler) backfillProgress(store backfillStore, opts BackfillOptions) (*models.BackfillProgress, error) {
+	name := opts.Name
+	if name == "" {
+		name = defaultBackfillName
+	}
+
+	stored, err := store.GetBackfillProgress(c.site.Name, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backfill progress: %w", err)
+	}
+	if stored != nil &&
+		(opts.FromID == 0 || opts.FromID == stored.StartID) &&
+		(opts.ToID == 0 || opts.ToID == stored.EndID) {
+		if opts.FromID != 0 || stored.NextID >= stored.EndID {
+			log.Printf("Resuming backfill %q at ID %d", name, stored.NextID)
+			return stored, nil
+		}
+
+		// The job is finished; continue with the torrents added since it started
+		maxID, err := c.backfillMaxID()
+		if err != nil {
+			return nil, err
+		}
+		if maxID <= stored.StartID {
+			log.Printf("Backfill %q is complete and no newer torrents are stored", name)
+			return stored, nil
+		}
+		log.Printf("Backfill %q is complete, continuing from ID %d down to %d", name, maxID, stored.StartID+1)
+		return &models.BackfillProgress{Site: c.site.Name, Name: name, StartID: maxID, EndID: stored.StartID + 1, NextID: maxID}, nil
+	}
+
+	start := opts.FromID
+	if start == 0 {
+		if start, err = c.backfillMaxID(); err != nil {
+			return nil, err
+		}
+	}
+
+	end := opts.ToID
+	if end == 0 {
+		end = 1
+	}
+	if end > start {
+		return nil, fmt.Errorf("backfill range is empty: from %d down to %d", start, end)
+	}
+
+	return &models.BackfillProgress{Site: c.site.Name, Name: name, StartID: start, EndID: end, NextID: start}, nil
+}
+
+// backfillMaxID returns the highest stored torrent ID, where a backfill
+// without a starting ID begins
+func (c *Crawler) backfillMaxID() (int, error) {
+	reader, ok := c.dbs.(maxIDReader)
+	if !ok {
+		return 0, fmt.Errorf("a starting ID is required when the database cannot report its highest ID")
+	}
+	maxID, err := reader.GetMaxTorrentID(c.site.Name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read highest torrent ID: %w", err)
+	}
+	if maxID == 0 {
+		return 0, fmt.Errorf("no torrents stored yet, a starting ID is required")
+	}
+	return maxID, nil
+}
@@ -0,0 +1,187 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"nyaa-crawler/pkg/models"
+)
+
+// mockBackfillStore adds backfill progress and missing ID tracking to mockTorrentInserter
+type mockBackfillStore struct {
+	mockTorrentInserter
+	maxID    int
+	progress map[string]models.BackfillProgress
+	missing  map[int]int
+}
+
+func newMockBackfillStore(maxID int) *mockBackfillStore {
+	return &mockBackfillStore{
+		maxID:    maxID,
+		progress: make(map[string]models.BackfillProgress),
+		missing:  make(map[int]int),
+	}
+}
+
+func (m *mockBackfillStore) GetMaxTorrentID(site string) (int, error) {
+	return m.maxID, nil
+}
+
+func (m *mockBackfillStore) GetBackfillProgress(site, name string) (*models.BackfillProgress, error) {
+	p, ok := m.progress[name]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (m *mockBackfillStore) SaveBackfillProgress(progress models.BackfillProgress) error {
+	m.progress[progress.Name] = progress
+	return nil
+}
+
+func (m *mockBackfillStore) MarkTorrentMissing(site string, id int, statusCode int) error {
+	m.missing[id] = statusCode
+	return nil
+}
+
+// newDetailServer serves detail pages for the given IDs and 404 for all others
+func newDetailServer(t *testing.T, ids ...int) *httptest.Server {
+	t.Helper()
+	existing := make(map[int]bool)
+	for _, id := range ids {
+		existing[id] = true
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/view/"))
+		if err != nil || !existing[id] {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprint(w, detailHTML(id))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBackfill(t *testing.T) {
+	server := newDetailServer(t, 10, 8)
+	store := newMockBackfillStore(10)
+
+	c, err := NewCrawler(WithDB(store), WithBaseURL(server.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	result, err := c.Backfill(context.Background(), BackfillOptions{ToID: 7})
+	if err != nil {
+		t.Fatalf("Backfill returned error: %v", err)
+	}
+
+	if result.Visited != 4 || result.Stored != 2 || result.Missing != 2 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if store.missing[9] != http.StatusNotFound || store.missing[7] != http.StatusNotFound {
+		t.Errorf("expected 9 and 7 recorded as missing, got %v", store.missing)
+	}
+	if p := store.progress[defaultBackfillName]; p.StartID != 10 || p.EndID != 7 || p.NextID != 6 {
+		t.Errorf("unexpected stored progress: %+v", p)
+	}
+
+	// A second run resumes the finished job and does nothing
+	result, err = c.Backfill(context.Background(), BackfillOptions{})
+	if err != nil {
+		t.Fatalf("Backfill resume returned error: %v", err)
+	}
+	if result.Visited != 0 {
+		t.Errorf("expected completed job to visit nothing, got %d", result.Visited)
+	}
+}
+
+func TestBackfillContinuesFinishedJob(t *testing.T) {
+	server := newDetailServer(t, 12, 11, 10)
+	store := newMockBackfillStore(12)
+	store.progress[defaultBackfillName] = models.BackfillProgress{Name: defaultBackfillName, StartID: 10, EndID: 1, NextID: 0}
+
+	c, err := NewCrawler(WithDB(store), WithBaseURL(server.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	result, err := c.Backfill(context.Background(), BackfillOptions{})
+	if err != nil {
+		t.Fatalf("Backfill returned error: %v", err)
+	}
+	if result.Visited != 2 || len(store.Torrents) != 2 || store.Torrents[0].ID != 12 || store.Torrents[1].ID != 11 {
+		t.Errorf("expected to visit only 12 and 11, got %+v / %+v", result, store.Torrents)
+	}
+	if p := store.progress[defaultBackfillName]; p.StartID != 12 || p.EndID != 11 || p.NextID != 10 {
+		t.Errorf("unexpected stored progress: %+v", p)
+	}
+
+	// An explicit starting ID still resumes the finished job as is
+	result, err = c.Backfill(context.Background(), BackfillOptions{FromID: 12})
+	if err != nil {
+		t.Fatalf("Backfill returned error: %v", err)
+	}
+	if result.Visited != 0 {
+		t.Errorf("expected an explicit start to visit nothing, got %d", result.Visited)
+	}
+}
+
+func TestBackfillResumes(t *testing.T) {
+	server := newDetailServer(t, 5, 4, 3)
+	store := newMockBackfillStore(0)
+	store.progress["archive"] = models.BackfillProgress{Name: "archive", StartID: 6, EndID: 3, NextID: 4}
+
+	c, err := NewCrawler(WithDB(store), WithBaseURL(server.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	result, err := c.Backfill(context.Background(), BackfillOptions{Name: "archive"})
+	if err != nil {
+		t.Fatalf("Backfill returned error: %v", err)
+	}
+	if result.Visited != 2 || len(store.Torrents) != 2 || store.Torrents[0].ID != 4 {
+		t.Errorf("expected to resume at ID 4 and store 4 and 3, got %+v / %+v", result, store.Torrents)
+	}
+}
+
+func TestBackfillStopsOnServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	store := newMockBackfillStore(0)
+	c, err := NewCrawler(WithDB(store), WithBaseURL(server.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	if _, err := c.Backfill(context.Background(), BackfillOptions{FromID: 20, ToID: 10}); err == nil {
+		t.Fatal("expected error when the server is unavailable")
+	}
+	if len(store.missing) != 0 {
+		t.Errorf("server errors must not be recorded as missing, got %v", store.missing)
+	}
+	if _, saved := store.progress[defaultBackfillName]; saved {
+		t.Error("progress must not advance past an ID that failed")
+	}
+}
+
+func TestBackfillRequiresStore(t *testing.T) {
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.Backfill(context.Background(), BackfillOptions{FromID: 10}); err == nil {
+		t.Error("expected error when the database cannot store progress")
+	}
+}
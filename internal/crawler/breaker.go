@@ -0,0 +1,217 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without making a request while the circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every request fast until the cooldown has passed
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe request through to test the upstream
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// circuitBreaker opens after threshold consecutive failed requests. Once the
+// cooldown has passed it half-opens and lets one probe through: success closes
+// it, failure opens it for another cooldown.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	// host names the upstream in log messages
+	host string
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// breakerSet keeps a circuit breaker per host, so a dead mirror fails fast
+// without stopping requests to the other mirrors
+type breakerSet struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*circuitBreaker
+}
+
+func newBreakerSet(threshold int, cooldown time.Duration) *breakerSet {
+	return &breakerSet{threshold: threshold, cooldown: cooldown, hosts: make(map[string]*circuitBreaker)}
+}
+
+// get returns the breaker of host, creating a closed one on first use
+func (s *breakerSet) get(host string) *circuitBreaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.hosts[host]
+	if !ok {
+		b = newCircuitBreaker(s.threshold, s.cooldown)
+		b.host = host
+		s.hosts[host] = b
+	}
+	return b
+}
+
+// state reports BreakerOpen only when every host's breaker is open, and
+// BreakerClosed when any is closed or no host has been requested yet
+func (s *breakerSet) state() BreakerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.hosts) == 0 {
+		return BreakerClosed
+	}
+	state := BreakerOpen
+	for _, b := range s.hosts {
+		switch b.currentState() {
+		case BreakerClosed:
+			return BreakerClosed
+		case BreakerHalfOpen:
+			state = BreakerHalfOpen
+		}
+	}
+	return state
+}
+
+// WithCircuitBreaker stops requests to a host for cooldown after threshold
+// consecutive failures (network errors or retryable status codes). Each
+// host, and so each mirror, has its own breaker. A threshold of 0 disables
+// the breakers.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Crawler) error {
+		if threshold <= 0 {
+			c.breakers = nil
+			return nil
+		}
+		if cooldown <= 0 {
+			return fmt.Errorf("circuit breaker cooldown must be positive, got %v", cooldown)
+		}
+		c.breakers = newBreakerSet(threshold, cooldown)
+		return nil
+	}
+}
+
+// BreakerState reports the state of the circuit breakers, so callers such as
+// a scheduler can skip runs while the upstream is down. It is BreakerOpen
+// only while the breaker of every host requested so far is open, and always
+// BreakerClosed when no breaker is configured.
+func (c *Crawler) BreakerState() BreakerState {
+	if c.breakers == nil {
+		return BreakerClosed
+	}
+	return c.breakers.state()
+}
+
+// currentState returns the state, reporting an open breaker whose cooldown
+// has passed as half-open
+func (b *circuitBreaker) currentState() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a request may be made. Every allowed request must be
+// followed by success, failure or cancel.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		remaining := b.cooldown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return fmt.Errorf("%w (retrying in %v)", ErrCircuitOpen, remaining.Round(time.Second))
+		}
+		b.setState(BreakerHalfOpen)
+	}
+	if b.state == BreakerHalfOpen {
+		if b.probing {
+			return fmt.Errorf("%w (probe in progress)", ErrCircuitOpen)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// success records a request that reached a healthy upstream
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+	b.setState(BreakerClosed)
+}
+
+// failure records a failed request, opening the breaker at the threshold or
+// when a half-open probe fails
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// cancel records a request abandoned before it had an outcome, freeing the
+// probe slot when half-open
+func (b *circuitBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// setState changes the state and logs the transition; callers hold b.mu
+func (b *circuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	name := "Circuit breaker"
+	if b.host != "" {
+		name += " for " + b.host
+	}
+	switch state {
+	case BreakerOpen:
+		log.Printf("%s open after %d consecutive failures, failing fast for %v", name, b.failures, b.cooldown)
+	case BreakerHalfOpen:
+		log.Printf("%s half-open, probing upstream", name)
+	case BreakerClosed:
+		log.Printf("%s closed, upstream recovered", name)
+	}
+	b.state = state
+}
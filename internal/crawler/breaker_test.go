@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Date(2026, 1, 13, 12, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("request %d: expected closed breaker to allow, got %v", i+1, err)
+		}
+		b.failure()
+	}
+	if s := b.currentState(); s != BreakerOpen {
+		t.Fatalf("expected open after 2 failures, got %s", s)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen during cooldown, got %v", err)
+	}
+
+	// After the cooldown a single probe is let through
+	now = now.Add(time.Minute)
+	if s := b.currentState(); s != BreakerHalfOpen {
+		t.Fatalf("expected half-open after cooldown, got %s", s)
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected second request during probe to fail fast, got %v", err)
+	}
+
+	// A failed probe reopens for another cooldown
+	b.failure()
+	if s := b.currentState(); s != BreakerOpen {
+		t.Fatalf("expected open after failed probe, got %s", s)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected second probe to be allowed, got %v", err)
+	}
+	b.success()
+	if s := b.currentState(); s != BreakerClosed {
+		t.Fatalf("expected closed after successful probe, got %s", s)
+	}
+
+	// Failures must be consecutive to open again
+	b.failure()
+	b.success()
+	b.failure()
+	if s := b.currentState(); s != BreakerClosed {
+		t.Errorf("expected non-consecutive failures to keep the breaker closed, got %s", s)
+	}
+}
+
+func TestCircuitBreakerCancelFreesProbe(t *testing.T) {
+	now := time.Date(2026, 1, 13, 12, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	now = now.Add(time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	b.cancel()
+	if err := b.allow(); err != nil {
+		t.Errorf("expected a new probe after cancellation, got %v", err)
+	}
+}
+
+func TestFetchFailsFastWhenBreakerOpen(t *testing.T) {
+	server, hits := newStatusServer(t, nil, 500, 500, 500, 500, 500, 500)
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	c, err := NewCrawler(
+		WithDB(&mockTorrentInserter{}),
+		WithRetryPolicy(policy),
+		WithMaxRetries(3),
+		WithCircuitBreaker(2, time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	_, err = c.fetchWithRetry(context.Background(), server.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the breaker to cut retries short, got %v", err)
+	}
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("expected 2 requests before the breaker opened, got %d", n)
+	}
+	if s := c.BreakerState(); s != BreakerOpen {
+		t.Errorf("expected breaker state open, got %s", s)
+	}
+
+	// Later pages fail without touching the upstream
+	if _, err := c.ScrapePages(context.Background(), server.URL, 3); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen from ScrapePages, got %v", err)
+	}
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("expected no requests while open, got %d", n)
+	}
+}
+
+func TestBreakerIgnoresNotFound(t *testing.T) {
+	server, _ := newStatusServer(t, nil, 404, 404, 404)
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithCircuitBreaker(2, time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.fetchWithRetry(context.Background(), server.URL); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("request %d: expected ErrNotFound, got %v", i+1, err)
+		}
+	}
+	if s := c.BreakerState(); s != BreakerClosed {
+		t.Errorf("expected 404s to keep the breaker closed, got %s", s)
+	}
+}
@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// validators are the cache validators a server sent with a page
+type validators struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// apply adds the conditional request headers for v to req
+func (v validators) apply(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// validatorCache stores the ETag and Last-Modified of listing pages by URL,
+// in memory and optionally as one JSON file per URL in a directory so the
+// validators survive between runs
+type validatorCache struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string]validators
+}
+
+func newValidatorCache(dir string) (*validatorCache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating cache directory: %w", err)
+		}
+	}
+	return &validatorCache{dir: dir, entries: make(map[string]validators)}, nil
+}
+
+// WithHTTPCache sends If-None-Match/If-Modified-Since for listing pages that
+// were fetched before. Unchanged pages (304) are not parsed and are reported
+// with PageResult.NotModified. With a non-empty dir the validators are also
+// kept on disk.
+func WithHTTPCache(dir string) Option {
+	return func(c *Crawler) error {
+		cache, err := newValidatorCache(dir)
+		if err != nil {
+			return err
+		}
+		c.cache = cache
+		return nil
+	}
+}
+
+// path returns the cache file of a URL
+func (vc *validatorCache) path(targetURL string) string {
+	sum := sha256.Sum256([]byte(targetURL))
+	return filepath.Join(vc.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the validators stored for targetURL
+func (vc *validatorCache) get(targetURL string) (validators, bool) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if v, ok := vc.entries[targetURL]; ok {
+		return v, true
+	}
+	if vc.dir == "" {
+		return validators{}, false
+	}
+
+	data, err := os.ReadFile(vc.path(targetURL))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to read HTTP cache entry for %s: %v", targetURL, err)
+		}
+		return validators{}, false
+	}
+	var v validators
+	if err := json.Unmarshal(data, &v); err != nil || v.URL != targetURL {
+		return validators{}, false
+	}
+	vc.entries[targetURL] = v
+	return v, true
+}
+
+// responseValidators returns the validators a 200 response carried
+func responseValidators(targetURL string, header http.Header) validators {
+	return validators{URL: targetURL, ETag: header.Get("ETag"), LastModified: header.Get("Last-Modified")}
+}
+
+// store records the validators of a 200 response, or forgets the URL when
+// the server sent none
+func (vc *validatorCache) store(v validators) {
+	targetURL := v.URL
+	if v.ETag == "" && v.LastModified == "" {
+		vc.forget(targetURL)
+		return
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.entries[targetURL] = v
+	if vc.dir == "" {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = os.WriteFile(vc.path(targetURL), data, 0o644)
+	}
+	if err != nil {
+		log.Printf("Failed to write HTTP cache entry for %s: %v", targetURL, err)
+	}
+}
+
+// forget drops the validators stored for targetURL
+func (vc *validatorCache) forget(targetURL string) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	delete(vc.entries, targetURL)
+	if vc.dir != "" {
+		_ = os.Remove(vc.path(targetURL))
+	}
+}
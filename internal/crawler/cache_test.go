@@ -0,0 +1,191 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"nyaa-crawler/pkg/models"
+)
+
+// conditionalServer serves a listing page with an ETag and Last-Modified
+// date, answering 304 when the client already has it
+type conditionalServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	etag        string
+	conditional []string // If-None-Match of every request
+}
+
+func newConditionalServer(t *testing.T, ids ...int) *conditionalServer {
+	t.Helper()
+	s := &conditionalServer{etag: `"v1"`}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		etag := s.etag
+		s.conditional = append(s.conditional, r.Header.Get("If-None-Match"))
+		s.mu.Unlock()
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Tue, 13 Jan 2026 12:00:00 GMT")
+		_, _ = fmt.Fprint(w, listingHTML(ids...))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *conditionalServer) setETag(etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etag = etag
+}
+
+func (s *conditionalServer) lastIfNoneMatch() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conditional[len(s.conditional)-1]
+}
+
+func TestConditionalListingRequests(t *testing.T) {
+	server := newConditionalServer(t, 3, 2, 1)
+	mockDB := &mockTorrentInserter{}
+	c, err := NewCrawler(WithDB(mockDB), WithHTTPCache(""))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	results, err := c.ScrapePages(context.Background(), server.URL, 1)
+	if err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+	if results[0].NotModified || results[0].Found != 3 {
+		t.Fatalf("expected a full first crawl, got %+v", results[0])
+	}
+	if got := server.lastIfNoneMatch(); got != "" {
+		t.Errorf("expected an unconditional first request, got If-None-Match %q", got)
+	}
+
+	results, err = c.ScrapePages(context.Background(), server.URL, 3)
+	if err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].NotModified || results[0].Found != 0 {
+		t.Fatalf("expected a single not-modified page, got %+v", results)
+	}
+	if got := server.lastIfNoneMatch(); got != `"v1"` {
+		t.Errorf("expected If-None-Match \"v1\", got %q", got)
+	}
+
+	// A changed page is parsed again
+	server.setETag(`"v2"`)
+	results, err = c.ScrapePages(context.Background(), server.URL, 1)
+	if err != nil {
+		t.Fatalf("third crawl failed: %v", err)
+	}
+	if results[0].NotModified || results[0].Found != 3 {
+		t.Errorf("expected the changed page to be parsed, got %+v", results[0])
+	}
+}
+
+// failingInserter fails every insert while fail is set
+type failingInserter struct {
+	mockTorrentInserter
+	fail bool
+}
+
+func (f *failingInserter) InsertTorrents(torrents []models.Torrent) (int, error) {
+	if f.fail {
+		return 0, errors.New("database unavailable")
+	}
+	return f.mockTorrentInserter.InsertTorrents(torrents)
+}
+
+func TestFailedPagesAreNotCached(t *testing.T) {
+	server := newConditionalServer(t, 3, 2, 1)
+	db := &failingInserter{fail: true}
+	c, err := NewCrawler(WithDB(db), WithHTTPCache(""))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	if _, err := c.ScrapePages(context.Background(), server.URL, 1); err == nil {
+		t.Fatal("expected the crawl to fail while the database is down")
+	}
+
+	// The page was never stored, so it must not be answered with 304 next time
+	db.fail = false
+	results, err := c.ScrapePages(context.Background(), server.URL, 1)
+	if err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+	if got := server.lastIfNoneMatch(); got != "" {
+		t.Errorf("expected an unconditional request after the failure, got If-None-Match %q", got)
+	}
+	if results[0].NotModified || results[0].Inserted != 3 {
+		t.Errorf("expected the page to be stored, got %+v", results[0])
+	}
+
+	// Once stored, the validators are used
+	if _, err := c.ScrapePages(context.Background(), server.URL, 1); err != nil {
+		t.Fatalf("third crawl failed: %v", err)
+	}
+	if got := server.lastIfNoneMatch(); got != `"v1"` {
+		t.Errorf("expected If-None-Match \"v1\", got %q", got)
+	}
+}
+
+func TestConditionalCacheOnDisk(t *testing.T) {
+	server := newConditionalServer(t, 2, 1)
+	dir := t.TempDir()
+
+	first, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithHTTPCache(dir))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := first.ScrapePages(context.Background(), server.URL, 1); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 cache file, got %d (%v)", len(entries), err)
+	}
+
+	// A new crawler picks the validators up from disk
+	second, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithHTTPCache(dir))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	results, err := second.ScrapeIncremental(context.Background(), server.URL, 5)
+	if err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].NotModified {
+		t.Errorf("expected the incremental crawl to stop at the unchanged page, got %+v", results)
+	}
+}
+
+func TestDetailRequestsAreUnconditional(t *testing.T) {
+	server := newConditionalServer(t, 7)
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithHTTPCache(""), WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.fetch(context.Background(), c.viewURL(7), false); err != nil {
+			t.Fatalf("fetch %d failed: %v", i+1, err)
+		}
+		if got := server.lastIfNoneMatch(); got != "" {
+			t.Errorf("fetch %d: expected no If-None-Match, got %q", i+1, got)
+		}
+	}
+}
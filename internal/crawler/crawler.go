@@ -1,23 +1,25 @@
 package crawler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"nyaa-crawler/pkg/magnet"
 	"nyaa-crawler/pkg/models"
 
 	"github.com/PuerkitoBio/goquery"
-	"golang.org/x/net/proxy"
 )
 
 // Pre-compiled regex for ID extraction
@@ -25,7 +27,34 @@ var idRegex = regexp.MustCompile(`/view/(\d+)`)
 
 // torrentInserter is the minimal database interface the crawler needs
 type torrentInserter interface {
-	InsertTorrents(torrents []models.Torrent) error
+	InsertTorrents(torrents []models.Torrent) (int, error)
+}
+
+// maxIDReader is implemented by database services that can report the
+// highest torrent ID already stored, used as the incremental high-water mark
+type maxIDReader interface {
+	GetMaxTorrentID(site string) (int, error)
+}
+
+// statsRecorder is implemented by database services that keep a swarm history
+type statsRecorder interface {
+	RecordTorrentStats(torrents []models.Torrent) error
+}
+
+// PageResult reports the outcome of scraping a single listing page
+type PageResult struct {
+	Page     int
+	URL      string
+	Found    int
+	Inserted int
+	LowestID int
+	// Malformed counts listing rows that failed to parse
+	Malformed int
+	// Mirror is the base URL of the mirror that served the page
+	Mirror string
+	// NotModified is set when the page was unchanged since the last crawl
+	// (HTTP 304) and was therefore not parsed
+	NotModified bool
 }
 
 // Crawler handles the scraping logic
@@ -33,6 +62,87 @@ type Crawler struct {
 	client     *http.Client
 	dbs        torrentInserter
 	maxRetries int
+	retry      RetryPolicy
+	source     Source
+	site       Site
+	baseURL    *url.URL
+	limiter    *rateLimiter
+	jitter     time.Duration
+	breakers   *breakerSet
+	proxies    *proxyPool
+	cache      *validatorCache
+	// snapshotDir keeps a copy of every parsed page (see WithSnapshotDir)
+	snapshotDir string
+	// layoutCheck fails listing scrapes that do not match the site layout
+	layoutCheck  bool
+	maxMalformed float64
+	// concurrency is the number of pages fetched in parallel (see WithConcurrency)
+	concurrency int
+
+	mirrors      []*url.URL
+	mirrorMu     sync.Mutex
+	activeMirror int
+}
+
+// Sentinel errors for errors.Is, so callers can tell why a fetch failed
+var (
+	// ErrNotFound matches 404 and 410 responses
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited matches 429 responses and 503 responses with Retry-After
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNetwork matches failures to get any response at all
+	ErrNetwork = errors.New("network error")
+	// ErrChallenge matches Cloudflare challenge pages served instead of content
+	ErrChallenge = errors.New("cloudflare challenge")
+	// ErrNotModified is returned for a conditional request answered with 304
+	ErrNotModified = errors.New("not modified")
+)
+
+// StatusError reports an HTTP response with a non-200 status code
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is the delay requested by the Retry-After header, if any
+	RetryAfter time.Duration
+	// Challenge is set when the response is a Cloudflare challenge page
+	Challenge bool
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code error: %d %s", e.StatusCode, e.Status)
+}
+
+// Is matches ErrNotFound and ErrRateLimited by status code
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests ||
+			(e.StatusCode == http.StatusServiceUnavailable && e.RetryAfter > 0)
+	case ErrChallenge:
+		return e.Challenge
+	}
+	return false
+}
+
+// NetworkError reports a request that failed without a response, such as a
+// DNS failure, refused connection or timeout
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrNetwork
+func (e *NetworkError) Is(target error) bool {
+	return target == ErrNetwork
 }
 
 // Option is a function that configures the Crawler
@@ -75,26 +185,37 @@ func WithProxy(proxyURL string) Option {
 			transport = &http.Transport{}
 		}
 
-		if proxyURLParsed.Scheme == "socks5" {
-			dialer, err := proxy.FromURL(proxyURLParsed, proxy.Direct)
-			if err != nil {
-				return fmt.Errorf("error creating SOCKS5 proxy dialer: %w", err)
-			}
-			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return dialer.Dial(network, addr)
-			}
-		} else {
-			transport.Proxy = http.ProxyURL(proxyURLParsed)
+		if err := applyProxy(transport, proxyURLParsed); err != nil {
+			return err
 		}
 		c.client.Transport = transport
-		log.Printf("Using proxy: %s", proxyURL)
+		log.Printf("Using proxy: %s", proxyURLParsed.Redacted())
 		return nil
 	}
 }
 
-// WithMaxRetries sets the maximum number of retries
+// applyProxy routes a transport through an http, https, socks5 or socks5h proxy
+func applyProxy(transport *http.Transport, proxyURL *url.URL) error {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		dial, err := socksDialer(proxyURL)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = nil
+		transport.DialContext = dial
+	default:
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return nil
+}
+
+// WithMaxRetries sets the maximum number of attempts per request
 func WithMaxRetries(maxRetries int) Option {
 	return func(c *Crawler) error {
+		if maxRetries < 1 {
+			return fmt.Errorf("max retries must be at least 1, got %d", maxRetries)
+		}
 		c.maxRetries = maxRetries
 		return nil
 	}
@@ -103,8 +224,14 @@ func WithMaxRetries(maxRetries int) Option {
 // NewCrawler creates a new crawler instance with options
 func NewCrawler(opts ...Option) (*Crawler, error) {
 	c := &Crawler{
-		client:     &http.Client{Timeout: 30 * time.Second},
-		maxRetries: 3,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		maxRetries:  3,
+		retry:       DefaultRetryPolicy(),
+		source:      SourceHTML,
+		concurrency: 1,
+	}
+	if err := WithSite(Nyaa)(c); err != nil {
+		return nil, err
 	}
 
 	for _, opt := range opts {
@@ -120,56 +247,352 @@ func NewCrawler(opts ...Option) (*Crawler, error) {
 	return c, nil
 }
 
-// fetchWithRetry performs HTTP GET with automatic retry on failure
-// Returns the response body reader if successful
-func (c *Crawler) fetchWithRetry(ctx context.Context, targetURL string) (io.ReadCloser, error) {
+// fetchWithRetry performs HTTP GET, retrying network errors and retryable
+// status codes as the retry policy allows. Returns the response body if
+// successful; failures are a *StatusError or *NetworkError. Cloudflare
+// challenges are never retried, since another attempt gets another challenge.
+func (c *Crawler) fetchWithRetry(ctx context.Context, targetURL string) ([]byte, error) {
+	body, _, err := c.request(ctx, targetURL, false)
+	return body, err
+}
+
+// request is fetchWithRetry with optional conditional GET: when conditional
+// is set and the HTTP cache holds validators for targetURL, they are sent
+// along and a 304 answer returns ErrNotModified. With the cache enabled, a
+// conditional request also returns the validators of the response; they are
+// not stored here, since the page may still fail to parse or be stored.
+func (c *Crawler) request(ctx context.Context, targetURL string, conditional bool) ([]byte, *validators, error) {
+	useCache := conditional && c.cache != nil
+	var cached validators
+	conditional = false
+	if useCache {
+		cached, conditional = c.cache.get(targetURL)
+	}
+
+	var breaker *circuitBreaker
+	if c.breakers != nil {
+		host := ""
+		if u, err := url.Parse(targetURL); err == nil {
+			host = u.Host
+		}
+		breaker = c.breakers.get(host)
+	}
+
 	var lastErr error
 
 	for attempt := 1; attempt <= c.maxRetries; attempt++ {
+		if err := c.throttle(ctx, targetURL); err != nil {
+			return nil, nil, err
+		}
+
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if conditional {
+			cached.apply(req)
+		}
+
+		if breaker != nil {
+			if err := breaker.allow(); err != nil {
+				return nil, nil, err
+			}
 		}
 
 		resp, err := c.client.Do(req)
-		if err == nil {
-			if resp.StatusCode == 200 {
-				return resp.Body, nil
+		if err != nil {
+			if ctx.Err() != nil {
+				if breaker != nil {
+					breaker.cancel()
+				}
+				return nil, nil, ctx.Err()
 			}
+			lastErr = &NetworkError{Err: err}
+			c.recordOutcome(breaker, nil, lastErr)
+		} else if resp.StatusCode == http.StatusNotModified && conditional {
+			_ = resp.Body.Close()
+			c.recordOutcome(breaker, resp, nil)
+			return nil, nil, ErrNotModified
+		} else if resp.StatusCode == http.StatusOK {
+			body, err := io.ReadAll(resp.Body)
 			_ = resp.Body.Close()
-			lastErr = fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+			if err == nil {
+				c.recordOutcome(breaker, resp, nil)
+				var fresh *validators
+				if useCache {
+					v := responseValidators(targetURL, resp.Header)
+					fresh = &v
+				}
+				return body, fresh, nil
+			}
+			if ctx.Err() != nil {
+				if breaker != nil {
+					breaker.cancel()
+				}
+				return nil, nil, ctx.Err()
+			}
+			lastErr = &NetworkError{Err: err}
+			c.recordOutcome(breaker, nil, lastErr)
 		} else {
-			lastErr = err
+			// Read enough of the error page to recognize a Cloudflare challenge
+			snippet, _ := io.ReadAll(io.LimitReader(resp.Body, challengeSnippetSize))
+			_ = resp.Body.Close()
+			statusErr := &StatusError{
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+				Challenge:  isChallenge(resp, snippet),
+			}
+			c.recordOutcome(breaker, resp, statusErr)
+			if statusErr.Challenge || !c.retry.Retryable(resp.StatusCode) {
+				return nil, nil, statusErr
+			}
+			lastErr = statusErr
 		}
 
-		if attempt < c.maxRetries {
-			backoff := time.Duration(attempt) * time.Second
-			log.Printf("Attempt %d failed, retrying in %v...", attempt, backoff)
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(backoff):
-			}
+		if attempt == c.maxRetries {
+			break
+		}
+		delay, ok := c.retry.delay(attempt, lastErr)
+		if !ok {
+			log.Printf("Attempt %d failed (%v), server asked to wait longer than %v; giving up", attempt, lastErr, c.retry.MaxDelay)
+			break
+		}
+		log.Printf("Attempt %d failed (%v), retrying in %v...", attempt, lastErr, delay)
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(delay):
 		}
 	}
 
-	return nil, lastErr
+	return nil, nil, lastErr
+}
+
+// recordOutcome feeds the result of a request to the host's circuit breaker.
+// Non-retryable statuses such as 404 still show the upstream is up.
+func (c *Crawler) recordOutcome(breaker *circuitBreaker, resp *http.Response, err error) {
+	if breaker == nil {
+		return
+	}
+	switch {
+	case err == nil:
+		breaker.success()
+	case errors.Is(err, ErrChallenge):
+		breaker.failure()
+	case resp != nil && !c.retry.Retryable(resp.StatusCode):
+		breaker.success()
+	default:
+		breaker.failure()
+	}
 }
 
 // ScrapePage scrapes a single page of torrents
 func (c *Crawler) ScrapePage(ctx context.Context, targetURL string) error {
-	body, err := c.fetchWithRetry(ctx, targetURL)
+	_, err := c.scrapeListing(ctx, targetURL)
+	return err
+}
+
+// ScrapePages follows Nyaa's ?p=N pagination starting from targetURL and scrapes
+// up to maxPages listing pages. It stops early when a page contains no torrents,
+// and returns the results of every page scraped before any error occurred.
+// With WithConcurrency, later pages are fetched while earlier ones are stored.
+func (c *Crawler) ScrapePages(ctx context.Context, targetURL string, maxPages int) ([]PageResult, error) {
+	if maxPages < 1 {
+		return nil, fmt.Errorf("page count must be at least 1, got %d", maxPages)
+	}
+	maxPages = c.pageLimit(maxPages)
+
+	var results []PageResult
+	err := c.crawlListings(ctx, targetURL, maxPages, func(result *PageResult) bool {
+		results = append(results, *result)
+
+		if result.NotModified {
+			log.Printf("Page %d not modified since the last crawl, stopping pagination", result.Page)
+			return false
+		}
+		if result.Found == 0 {
+			log.Printf("Page %d is empty, stopping pagination", result.Page)
+			return false
+		}
+		return true
+	})
+
+	return results, err
+}
+
+// ScrapeIncremental walks listing pages newest-first starting from targetURL,
+// up to maxPages, and stops as soon as a page holds only torrents that are
+// already stored or reaches IDs at or below the stored high-water mark.
+func (c *Crawler) ScrapeIncremental(ctx context.Context, targetURL string, maxPages int) ([]PageResult, error) {
+	if maxPages < 1 {
+		return nil, fmt.Errorf("page count must be at least 1, got %d", maxPages)
+	}
+	maxPages = c.pageLimit(maxPages)
+
+	highWater := 0
+	if reader, ok := c.dbs.(maxIDReader); ok {
+		maxID, err := reader.GetMaxTorrentID(c.site.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read high-water mark: %w", err)
+		}
+		highWater = maxID
+		log.Printf("Incremental crawl high-water mark: %d", highWater)
+	}
+
+	var results []PageResult
+	err := c.crawlListings(ctx, targetURL, maxPages, func(result *PageResult) bool {
+		results = append(results, *result)
+
+		switch {
+		case result.NotModified:
+			log.Printf("Page %d not modified since the last crawl, stopping incremental crawl", result.Page)
+			return false
+		case result.Found == 0:
+			log.Printf("Page %d is empty, stopping incremental crawl", result.Page)
+			return false
+		case result.Inserted == 0:
+			log.Printf("Page %d contains only known torrents, stopping incremental crawl", result.Page)
+			return false
+		case highWater > 0 && result.LowestID <= highWater:
+			log.Printf("Page %d reached high-water mark %d, stopping incremental crawl", result.Page, highWater)
+			return false
+		}
+		return true
+	})
+
+	return results, err
+}
+
+// crawlListings fetches pages 1..maxPages of targetURL, up to the crawler's
+// concurrency at a time, and stores them in page order. next is called with
+// every stored page and returns false to stop the crawl.
+func (c *Crawler) crawlListings(ctx context.Context, targetURL string, maxPages int, next func(*PageResult) bool) error {
+	fetchPage := func(ctx context.Context, i int) (*listingPage, error) {
+		pageTarget, err := pageURL(targetURL, i+1)
+		if err != nil {
+			return nil, err
+		}
+		return c.fetchListing(ctx, pageTarget)
+	}
+	storePage := func(i int, page *listingPage, err error) (bool, error) {
+		if err != nil {
+			return false, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		result, err := c.storeListing(page)
+		if err != nil {
+			return false, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		result.Page = i + 1
+		return next(result), nil
+	}
+	return runOrdered(ctx, c.concurrency, maxPages, fetchPage, storePage, nil)
+}
+
+// pageLimit caps the page count for sources that cannot paginate
+func (c *Crawler) pageLimit(maxPages int) int {
+	if c.source == SourceRSS && maxPages > 1 {
+		log.Printf("RSS feeds are not paginated, crawling a single page instead of %d", maxPages)
+		return 1
+	}
+	return maxPages
+}
+
+// listingPage is a fetched and parsed listing page that is not stored yet
+type listingPage struct {
+	result     PageResult
+	torrents   []models.Torrent
+	validators *validators
+}
+
+// scrapeListing fetches a listing page, then parses and inserts its torrents
+func (c *Crawler) scrapeListing(ctx context.Context, targetURL string) (*PageResult, error) {
+	page, err := c.fetchListing(ctx, targetURL)
 	if err != nil {
-		return fmt.Errorf("failed to fetch %s: %w", targetURL, err)
+		return nil, err
+	}
+	return c.storeListing(page)
+}
+
+// fetchListing fetches and parses a listing page without touching the database
+func (c *Crawler) fetchListing(ctx context.Context, targetURL string) (*listingPage, error) {
+	if c.source == SourceRSS {
+		feedURL, err := rssURL(targetURL)
+		if err != nil {
+			return nil, err
+		}
+		targetURL = feedURL
 	}
-	defer func() { _ = body.Close() }()
 
+	fetched, err := c.fetch(ctx, targetURL, true)
+	if errors.Is(err, ErrNotModified) {
+		return &listingPage{result: PageResult{URL: targetURL, NotModified: true}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", targetURL, err)
+	}
+	page := &listingPage{result: PageResult{URL: fetched.URL, Mirror: fetched.Mirror}, validators: fetched.Validators}
+	body := bytes.NewReader(fetched.Body)
+
+	if c.source == SourceRSS {
+		c.archive(SnapshotRSS, fetched)
+		if page.torrents, err = c.site.ParseRSS(body); err != nil {
+			return nil, err
+		}
+		return page, nil
+	}
+
+	c.archive(SnapshotListing, fetched)
 	doc, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	torrents, report, err := c.parseListing(doc)
+	if err != nil {
+		return nil, err
+	}
+	page.torrents = torrents
+	page.result.Malformed = report.Malformed
+	return page, nil
+}
+
+// storeListing inserts the torrents of a fetched listing page. Only then are
+// the page's cache validators stored, so a page that failed to parse or store
+// is fetched in full again by the next crawl instead of answered with 304.
+// Pages fetched ahead of the crawl but never stored leave the cache untouched.
+func (c *Crawler) storeListing(page *listingPage) (*PageResult, error) {
+	if page.result.NotModified {
+		result := page.result
+		return &result, nil
+	}
+	result, err := c.processTorrents(page.torrents)
+	if err != nil {
+		return nil, err
+	}
+	if page.validators != nil && c.cache != nil {
+		c.cache.store(*page.validators)
+	}
+	result.URL = page.result.URL
+	result.Mirror = page.result.Mirror
+	result.Malformed = page.result.Malformed
+	return result, nil
+}
+
+// pageURL returns targetURL with Nyaa's "p" pagination parameter set to page
+func pageURL(targetURL string, page int) (string, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing URL %q: %w", targetURL, err)
 	}
 
-	return c.processTorrentsFromDoc(doc)
+	q := u.Query()
+	if page > 1 {
+		q.Set("p", strconv.Itoa(page))
+	} else {
+		q.Del("p")
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
 }
 
 // ScrapeFromFile scrapes torrents from a local HTML file
@@ -185,48 +608,104 @@ func (c *Crawler) ScrapeFromFile(filePath string) error {
 		return err
 	}
 
-	return c.processTorrentsFromDoc(doc)
+	_, err = c.processTorrentsFromDoc(doc)
+	return err
+}
+
+// parseListing parses a listing page and applies the layout check
+func (c *Crawler) parseListing(doc *goquery.Document) ([]models.Torrent, LayoutReport, error) {
+	torrents, report := c.site.ParseListing(doc)
+	if err := c.checkLayout(report); err != nil {
+		return nil, report, err
+	}
+	if report.Malformed > 0 {
+		log.Printf("Warning: %d of %d listing rows failed to parse", report.Malformed, report.Rows)
+		if c.layoutCheck {
+			torrents = completeTorrents(torrents)
+		}
+	}
+	return torrents, report, nil
 }
 
 // processTorrentsFromDoc extracts and inserts torrents from a goquery.Document
-func (c *Crawler) processTorrentsFromDoc(doc *goquery.Document) error {
-	torrents := ParseTorrents(doc)
+func (c *Crawler) processTorrentsFromDoc(doc *goquery.Document) (*PageResult, error) {
+	torrents, report, err := c.parseListing(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.processTorrents(torrents)
+	if err != nil {
+		return nil, err
+	}
+	result.Malformed = report.Malformed
+	return result, nil
+}
 
+// processTorrents tags parsed torrents with the crawler's site, inserts them
+// and records their swarm statistics
+func (c *Crawler) processTorrents(torrents []models.Torrent) (*PageResult, error) {
+	result := c.newPageResult(torrents)
 	if len(torrents) == 0 {
 		log.Println("No torrents found on page")
-		return nil
+		return result, nil
 	}
 
 	// Batch insert all torrents
-	if err := c.dbs.InsertTorrents(torrents); err != nil {
-		return fmt.Errorf("failed to insert torrents: %w", err)
+	inserted, err := c.dbs.InsertTorrents(torrents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert torrents: %w", err)
 	}
+	result.Inserted = inserted
 
-	return nil
+	// Swarm history is best-effort and must not fail the crawl
+	if recorder, ok := c.dbs.(statsRecorder); ok {
+		if err := recorder.RecordTorrentStats(torrents); err != nil {
+			log.Printf("Warning: failed to record torrent stats: %v", err)
+		}
+	}
+
+	return result, nil
 }
 
-// ParseTorrents extracts all torrents from a goquery.Document
+// newPageResult tags parsed torrents with the crawler's site and starts the
+// result that reports them
+func (c *Crawler) newPageResult(torrents []models.Torrent) *PageResult {
+	result := &PageResult{Found: len(torrents)}
+	for i := range torrents {
+		torrents[i].Site = c.site.Name
+		if result.LowestID == 0 || torrents[i].ID < result.LowestID {
+			result.LowestID = torrents[i].ID
+		}
+	}
+	return result
+}
+
+// ParseTorrents extracts all torrents from a nyaa.si goquery.Document
 func ParseTorrents(doc *goquery.Document) []models.Torrent {
-	var torrents []models.Torrent
+	return Nyaa.ParseTorrents(doc)
+}
 
-	doc.Find("tbody tr").Each(func(i int, s *goquery.Selection) {
-		torrent := ParseTorrentRow(s)
-		if torrent != nil {
-			torrents = append(torrents, *torrent)
-		}
-	})
+// ParseTorrentRow parses a single nyaa.si table row to extract torrent information
+func ParseTorrentRow(row *goquery.Selection) *models.Torrent {
+	return Nyaa.ParseTorrentRow(row)
+}
 
+// ParseTorrents extracts all torrents from a goquery.Document using the site's selectors
+func (s Site) ParseTorrents(doc *goquery.Document) []models.Torrent {
+	torrents, _ := s.ParseListing(doc)
 	return torrents
 }
 
-// ParseTorrentRow parses a single table row to extract torrent information
-func ParseTorrentRow(row *goquery.Selection) *models.Torrent {
+// ParseTorrentRow parses a single table row using the site's selectors
+func (s Site) ParseTorrentRow(row *goquery.Selection) *models.Torrent {
 	torrent := &models.Torrent{}
+	sel := s.Selectors
 
 	// Extract name and ID
 	// For rows with comments, the title link is the second one
 	// For rows without comments, it's the first one
-	titleLinks := row.Find("td:nth-child(2) a")
+	titleLinks := row.Find(sel.Title)
 	titleLink := titleLinks.First()
 
 	// Check if first link is a comments link
@@ -241,7 +720,7 @@ func ParseTorrentRow(row *goquery.Selection) *models.Torrent {
 	// Extract ID from the view link using pre-compiled regex
 	href, exists := titleLink.Attr("href")
 	if exists {
-		matches := idRegex.FindStringSubmatch(href)
+		matches := s.IDPattern.FindStringSubmatch(href)
 		if len(matches) > 1 {
 			id, err := strconv.Atoi(matches[1])
 			if err != nil {
@@ -256,26 +735,44 @@ func ParseTorrentRow(row *goquery.Selection) *models.Torrent {
 	torrent.Name = strings.TrimSpace(titleLink.Text())
 
 	// Extract category from the image alt text
-	catLink := row.Find("td:first-child a")
+	catLink := row.Find(sel.Category)
 	catTitle, exists := catLink.Attr("title")
 	if exists {
 		torrent.Category = catTitle
 	}
+	if catHref, exists := catLink.Attr("href"); exists {
+		torrent.CategoryID = categoryIDFromHref(catHref)
+	}
+	if torrent.Category == "" {
+		torrent.Category = s.Categories[torrent.CategoryID]
+	}
+
+	// Trusted and remake uploads are highlighted by the row class
+	torrent.Trusted = row.HasClass("success")
+	torrent.Remake = row.HasClass("danger")
 
 	// Extract magnet link
-	row.Find("td:nth-child(3) a").Each(func(i int, link *goquery.Selection) {
+	row.Find(sel.Links).Each(func(i int, link *goquery.Selection) {
 		href, exists := link.Attr("href")
 		if exists && strings.HasPrefix(href, "magnet:") {
 			torrent.Magnet = href
 		}
 	})
+	torrent.InfoHash = magnet.InfoHash(torrent.Magnet)
 
 	// Extract size
-	torrent.Size = strings.TrimSpace(row.Find("td:nth-child(4)").Text())
+	torrent.Size = strings.TrimSpace(row.Find(sel.Size).Text())
+	torrent.SizeBytes = parseSizeBytes(torrent.Size)
 
 	// Extract date
-	dateCell := row.Find("td:nth-child(5)")
+	dateCell := row.Find(sel.Date)
 	torrent.Date = strings.TrimSpace(dateCell.Text())
+	torrent.PublishedAt = parseTimestamp(dateCell, torrent.Date)
+
+	// Extract swarm statistics
+	torrent.Seeders = parseCount(row.Find(sel.Seeders))
+	torrent.Leechers = parseCount(row.Find(sel.Leechers))
+	torrent.Completed = parseCount(row.Find(sel.Completed))
 
 	// Validate that we got a valid ID
 	if torrent.ID > 0 {
@@ -284,3 +781,59 @@ func ParseTorrentRow(row *goquery.Selection) *models.Torrent {
 
 	return nil
 }
+
+// parseCount parses a numeric table cell, returning 0 if it is missing or malformed
+func parseCount(cell *goquery.Selection) int {
+	text := strings.TrimSpace(cell.Text())
+	if text == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		log.Printf("Warning: failed to parse count %q: %v", text, err)
+		return 0
+	}
+	return n
+}
+
+// parseSizeBytes parses a size cell, returning 0 if it is missing or malformed
+func parseSizeBytes(text string) int64 {
+	if text == "" {
+		return 0
+	}
+	n, err := ParseSize(text)
+	if err != nil {
+		log.Printf("Warning: failed to parse size: %v", err)
+		return 0
+	}
+	return n
+}
+
+// dateLayout is the UTC date format of the listing and detail pages, which
+// RSS dates are converted to as well
+const dateLayout = "2006-01-02 15:04"
+
+// parseTimestamp reads the upload time from a date cell's data-timestamp
+// attribute, which holds Unix seconds, falling back to the UTC date text for
+// pages saved without it. It returns the zero time if neither parses.
+func parseTimestamp(cell *goquery.Selection, text string) time.Time {
+	if attr, exists := cell.Attr("data-timestamp"); exists {
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(attr), 10, 64); err == nil {
+			return time.Unix(seconds, 0).UTC()
+		}
+		log.Printf("Warning: failed to parse timestamp %q", attr)
+	}
+	if published, err := time.Parse(dateLayout, text); err == nil {
+		return published
+	}
+	return time.Time{}
+}
+
+// categoryIDFromHref extracts the category code from a link such as "/?c=1_2"
+func categoryIDFromHref(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("c")
+}
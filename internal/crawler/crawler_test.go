@@ -2,10 +2,17 @@ package crawler
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"nyaa-crawler/pkg/models"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 func TestIDRegex(t *testing.T) {
@@ -64,13 +71,30 @@ func TestNewCrawlerWithoutDB(t *testing.T) {
 }
 
 // mockTorrentInserter is a mock implementation of torrentInserter for testing
+// It skips IDs it has already seen, mirroring ON CONFLICT DO NOTHING
 type mockTorrentInserter struct {
 	Torrents []models.Torrent
 }
 
-func (m *mockTorrentInserter) InsertTorrents(torrents []models.Torrent) error {
-	m.Torrents = append(m.Torrents, torrents...)
-	return nil
+func (m *mockTorrentInserter) InsertTorrents(torrents []models.Torrent) (int, error) {
+	inserted := 0
+	for _, t := range torrents {
+		if m.has(t.ID) {
+			continue
+		}
+		m.Torrents = append(m.Torrents, t)
+		inserted++
+	}
+	return inserted, nil
+}
+
+func (m *mockTorrentInserter) has(id int) bool {
+	for _, t := range m.Torrents {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
 }
 
 func TestNewCrawlerWithMockDB(t *testing.T) {
@@ -101,3 +125,263 @@ func TestCrawlerScrapeTimeout(t *testing.T) {
 		t.Error("Expected error for cancelled context, got nil")
 	}
 }
+
+// listingRow renders a single Nyaa listing table row for the given torrent ID
+func listingRow(id int) string {
+	return fmt.Sprintf(`<tr class="default">
+<td><a href="/?c=1_2" title="Anime - English-translated"><img src="/static/img/icons/nyaa/1_2.png" alt="Anime - English-translated" class="category-icon"></a></td>
+<td colspan="2"><a href="/view/%[1]d#comments" class="comments" title="2 comments"><i class="fa fa-comments-o"></i>2</a><a href="/view/%[1]d" title="Torrent %[1]d">Torrent %[1]d</a></td>
+<td class="text-center"><a href="/download/%[1]d.torrent"><i class="fa fa-fw fa-download"></i></a><a href="magnet:?xt=urn:btih:%040[1]d"><i class="fa fa-fw fa-magnet"></i></a></td>
+<td class="text-center">1.4 GiB</td>
+<td class="text-center" data-timestamp="1704110400">2024-01-01 12:00</td>
+<td class="text-center">10</td>
+<td class="text-center">2</td>
+<td class="text-center">100</td>
+</tr>`, id)
+}
+
+// listingHTML renders a Nyaa listing page containing the given torrent IDs
+func listingHTML(ids ...int) string {
+	var rows strings.Builder
+	for _, id := range ids {
+		rows.WriteString(listingRow(id))
+	}
+	return `<html><body><table class="table torrent-list"><thead><tr>
+<th class="hdr-category">Category</th><th class="hdr-name">Name</th><th class="hdr-comments"></th><th class="hdr-link">Link</th>
+<th class="hdr-size">Size</th><th class="hdr-date">Date</th><th class="hdr-seeders"></th>
+<th class="hdr-leechers"></th><th class="hdr-downloads"></th>
+</tr></thead><tbody>` + rows.String() + `</tbody></table></body></html>`
+}
+
+// newListingServer serves listing pages keyed by the "p" query parameter
+func newListingServer(t *testing.T, pages map[int][]int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := 1
+		if p := r.URL.Query().Get("p"); p != "" {
+			page, _ = strconv.Atoi(p)
+		}
+		_, _ = fmt.Fprint(w, listingHTML(pages[page]...))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPageURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		page     int
+		expected string
+	}{
+		{"https://nyaa.si/", 1, "https://nyaa.si/"},
+		{"https://nyaa.si/", 3, "https://nyaa.si/?p=3"},
+		{"https://nyaa.si/?q=test&p=5", 1, "https://nyaa.si/?q=test"},
+		{"https://nyaa.si/?q=test", 2, "https://nyaa.si/?p=2&q=test"},
+	}
+
+	for _, tt := range tests {
+		got, err := pageURL(tt.input, tt.page)
+		if err != nil {
+			t.Errorf("pageURL(%q, %d) returned error: %v", tt.input, tt.page, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("pageURL(%q, %d) = %q, want %q", tt.input, tt.page, got, tt.expected)
+		}
+	}
+}
+
+func TestScrapePages(t *testing.T) {
+	server := newListingServer(t, map[int][]int{
+		1: {30, 29, 28},
+		2: {27, 26},
+		3: {},
+		4: {25},
+	})
+
+	mockDB := &mockTorrentInserter{}
+	c, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	results, err := c.ScrapePages(context.Background(), server.URL+"/", 5)
+	if err != nil {
+		t.Fatalf("ScrapePages returned error: %v", err)
+	}
+
+	// Page 3 is empty, so page 4 must never be fetched
+	if len(results) != 3 {
+		t.Fatalf("expected 3 page results, got %d", len(results))
+	}
+	wantFound := []int{3, 2, 0}
+	for i, r := range results {
+		if r.Page != i+1 {
+			t.Errorf("result %d: expected page %d, got %d", i, i+1, r.Page)
+		}
+		if r.Found != wantFound[i] || r.Inserted != wantFound[i] {
+			t.Errorf("page %d: expected %d found/inserted, got %d/%d", r.Page, wantFound[i], r.Found, r.Inserted)
+		}
+	}
+	if len(mockDB.Torrents) != 5 {
+		t.Errorf("expected 5 torrents inserted, got %d", len(mockDB.Torrents))
+	}
+}
+
+func TestScrapePagesInvalidCount(t *testing.T) {
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.ScrapePages(context.Background(), "https://nyaa.si/", 0); err == nil {
+		t.Error("expected error for zero page count")
+	}
+}
+
+// mockHighWaterInserter adds a stored high-water mark to mockTorrentInserter
+type mockHighWaterInserter struct {
+	mockTorrentInserter
+	maxID int
+}
+
+func (m *mockHighWaterInserter) GetMaxTorrentID(site string) (int, error) {
+	return m.maxID, nil
+}
+
+func TestScrapeIncrementalStopsAtKnownPage(t *testing.T) {
+	server := newListingServer(t, map[int][]int{
+		1: {30, 29, 28},
+		2: {27, 26, 25},
+		3: {24, 23, 22},
+	})
+
+	mockDB := &mockTorrentInserter{Torrents: []models.Torrent{{ID: 27}, {ID: 26}, {ID: 25}}}
+	c, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	results, err := c.ScrapeIncremental(context.Background(), server.URL+"/", 10)
+	if err != nil {
+		t.Fatalf("ScrapeIncremental returned error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected to stop after 2 pages, got %d", len(results))
+	}
+	if results[1].Inserted != 0 {
+		t.Errorf("expected page 2 to insert nothing, got %d", results[1].Inserted)
+	}
+}
+
+func TestScrapeIncrementalStopsAtHighWaterMark(t *testing.T) {
+	server := newListingServer(t, map[int][]int{
+		1: {30, 29, 28},
+		2: {27, 26, 25},
+		3: {24, 23, 22},
+	})
+
+	// ID 26 is below the high-water mark even though it was never stored
+	mockDB := &mockHighWaterInserter{maxID: 26}
+	c, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	results, err := c.ScrapeIncremental(context.Background(), server.URL+"/", 10)
+	if err != nil {
+		t.Fatalf("ScrapeIncremental returned error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected to stop after 2 pages, got %d", len(results))
+	}
+	if results[1].LowestID != 25 {
+		t.Errorf("expected lowest ID 25 on page 2, got %d", results[1].LowestID)
+	}
+}
+
+func TestParseTorrents(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(listingHTML(42, 41)))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	torrents := ParseTorrents(doc)
+	if len(torrents) != 2 {
+		t.Fatalf("expected 2 torrents, got %d", len(torrents))
+	}
+
+	got := torrents[0]
+	want := models.Torrent{
+		ID:          42,
+		Name:        "Torrent 42",
+		Magnet:      "magnet:?xt=urn:btih:0000000000000000000000000000000000000042",
+		InfoHash:    "0000000000000000000000000000000000000042",
+		Category:    "Anime - English-translated",
+		CategoryID:  "1_2",
+		Size:        "1.4 GiB",
+		Date:        "2024-01-01 12:00",
+		SizeBytes:   1503238554,
+		PublishedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Seeders:     10,
+		Leechers:    2,
+		Completed:   100,
+	}
+	if got != want {
+		t.Errorf("ParseTorrents()[0] = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<table><tr><td id="attr" data-timestamp="1704110400">2023-12-31 00:00</td><td id="text">2024-01-01 12:00</td><td id="none">yesterday</td></tr></table>`))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	want := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// The attribute wins over the text, which is only a fallback
+	for _, id := range []string{"attr", "text"} {
+		cell := doc.Find("#" + id)
+		if got := parseTimestamp(cell, strings.TrimSpace(cell.Text())); !got.Equal(want) {
+			t.Errorf("%s: got %v, want %v", id, got, want)
+		}
+	}
+	if got := parseTimestamp(doc.Find("#none"), "yesterday"); !got.IsZero() {
+		t.Errorf("expected the zero time for an unparsable date, got %v", got)
+	}
+}
+
+// mockStatsInserter adds swarm history recording to mockTorrentInserter
+type mockStatsInserter struct {
+	mockTorrentInserter
+	Recorded [][]models.Torrent
+}
+
+func (m *mockStatsInserter) RecordTorrentStats(torrents []models.Torrent) error {
+	m.Recorded = append(m.Recorded, torrents)
+	return nil
+}
+
+func TestScrapeRecordsStats(t *testing.T) {
+	server := newListingServer(t, map[int][]int{1: {30, 29}})
+
+	mockDB := &mockStatsInserter{}
+	c, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	// Scrape twice: the second pass inserts nothing but must still record history
+	for i := 0; i < 2; i++ {
+		if err := c.ScrapePage(context.Background(), server.URL+"/"); err != nil {
+			t.Fatalf("ScrapePage returned error: %v", err)
+		}
+	}
+
+	if len(mockDB.Recorded) != 2 {
+		t.Fatalf("expected 2 stats batches, got %d", len(mockDB.Recorded))
+	}
+	if got := mockDB.Recorded[1][0].Seeders; got != 10 {
+		t.Errorf("expected recorded seeders 10, got %d", got)
+	}
+}
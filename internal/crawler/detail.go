@@ -0,0 +1,255 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"nyaa-crawler/pkg/magnet"
+	"nyaa-crawler/pkg/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// commentCountRegex matches the trailing count in a "Comments - 5" heading
+var commentCountRegex = regexp.MustCompile(`(\d+)\s*$`)
+
+// detailWriter is implemented by database services that store detail page data
+type detailWriter interface {
+	SaveTorrentDetail(detail models.TorrentDetail) error
+}
+
+// archiveStore is implemented by database services that can store torrents
+// from old pages without overwriting rows that are already stored
+type archiveStore interface {
+	InsertMissingTorrents(torrents []models.Torrent) (int, error)
+	SaveMissingTorrentDetail(detail models.TorrentDetail) error
+}
+
+// WithBaseURL sets the site root used to build /view/{id} URLs,
+// overriding the base URL of the site profile
+func WithBaseURL(baseURL string) Option {
+	return func(c *Crawler) error {
+		u, err := parseBaseURL(baseURL)
+		if err != nil {
+			return err
+		}
+		c.baseURL = u
+		c.mirrors = []*url.URL{u}
+		c.setActiveMirror(0)
+		return nil
+	}
+}
+
+// viewURL returns the detail page URL for a torrent ID
+func (c *Crawler) viewURL(id int) string {
+	return c.baseURL.ResolveReference(&url.URL{Path: "/view/" + strconv.Itoa(id)}).String()
+}
+
+// ScrapeDetail fetches the /view/{id} page of a torrent, refreshes its listing
+// row and stores the detail data when the database service supports it
+func (c *Crawler) ScrapeDetail(ctx context.Context, id int) (*models.TorrentDetail, error) {
+	detail, err := c.fetchDetail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.storeRows([]models.Torrent{detail.Torrent}, []models.TorrentDetail{*detail}); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// fetchDetail fetches and parses the /view/{id} page of a torrent without
+// touching the database
+func (c *Crawler) fetchDetail(ctx context.Context, id int) (*models.TorrentDetail, error) {
+	targetURL := c.viewURL(id)
+	page, err := c.fetch(ctx, targetURL, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", targetURL, err)
+	}
+	c.archive(SnapshotDetail, page)
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page.Body))
+	if err != nil {
+		return nil, err
+	}
+	return c.parseDetail(doc, id)
+}
+
+// parseDetail parses a detail page of the crawler's site
+func (c *Crawler) parseDetail(doc *goquery.Document, id int) (*models.TorrentDetail, error) {
+	detail, err := ParseTorrentDetail(doc, id)
+	if err != nil {
+		return nil, err
+	}
+	detail.Torrent.Site = c.site.Name
+	if detail.Torrent.Category == "" {
+		detail.Torrent.Category = c.site.Categories[detail.Torrent.CategoryID]
+	}
+	return detail, nil
+}
+
+// storeRows inserts listing rows, then the detail data of detail pages when
+// the database service supports it
+func (c *Crawler) storeRows(torrents []models.Torrent, details []models.TorrentDetail) (*PageResult, error) {
+	result, err := c.processTorrents(torrents)
+	if err != nil {
+		return nil, err
+	}
+
+	if writer, ok := c.dbs.(detailWriter); ok {
+		for _, detail := range details {
+			detail.Torrent.Site = c.site.Name
+			if err := writer.SaveTorrentDetail(detail); err != nil {
+				return nil, fmt.Errorf("failed to save torrent detail: %w", err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// storeArchivedRows stores rows parsed from snapshots or saved pages. Their
+// swarm counts are only as current as the page, so unlike storeRows it fills
+// in missing torrents and details without touching stored ones, and records
+// no swarm history. Databases without archiveStore only get the inserts.
+func (c *Crawler) storeArchivedRows(torrents []models.Torrent, details []models.TorrentDetail) (*PageResult, error) {
+	result := c.newPageResult(torrents)
+	if len(torrents) == 0 {
+		return result, nil
+	}
+
+	store, ok := c.dbs.(archiveStore)
+	if !ok {
+		inserted, err := c.dbs.InsertTorrents(torrents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert torrents: %w", err)
+		}
+		result.Inserted = inserted
+		return result, nil
+	}
+
+	inserted, err := store.InsertMissingTorrents(torrents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert torrents: %w", err)
+	}
+	result.Inserted = inserted
+	for _, detail := range details {
+		detail.Torrent.Site = c.site.Name
+		if err := store.SaveMissingTorrentDetail(detail); err != nil {
+			return nil, fmt.Errorf("failed to save torrent detail: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// ParseTorrentDetail extracts torrent information from a /view/{id} page
+func ParseTorrentDetail(doc *goquery.Document, id int) (*models.TorrentDetail, error) {
+	panel := doc.Find("div.panel").First()
+	name := strings.TrimSpace(panel.Find(".panel-title").First().Text())
+	if name == "" {
+		return nil, fmt.Errorf("torrent %d: detail page has no title", id)
+	}
+
+	detail := &models.TorrentDetail{
+		Torrent: models.Torrent{
+			ID:      id,
+			Name:    name,
+			Trusted: panel.HasClass("panel-success"),
+			Remake:  panel.HasClass("panel-danger"),
+		},
+	}
+	t := &detail.Torrent
+
+	// Each field is a "Label:" cell followed by its value cell
+	panel.Find(".panel-body .row .col-md-1").Each(func(i int, label *goquery.Selection) {
+		value := label.Next()
+		switch strings.TrimSuffix(strings.TrimSpace(label.Text()), ":") {
+		case "Category":
+			t.Category = strings.Join(strings.Fields(value.Text()), " ")
+			if href, exists := value.Find("a").Last().Attr("href"); exists {
+				t.CategoryID = categoryIDFromHref(href)
+			}
+		case "Date":
+			t.Date = strings.TrimSuffix(strings.TrimSpace(value.Text()), " UTC")
+			t.PublishedAt = parseTimestamp(value, t.Date)
+		case "Submitter":
+			detail.Submitter = strings.TrimSpace(value.Text())
+		case "Information":
+			if href, exists := value.Find("a").Attr("href"); exists {
+				detail.InformationURL = href
+			}
+		case "Seeders":
+			t.Seeders = parseCount(value)
+		case "Leechers":
+			t.Leechers = parseCount(value)
+		case "File size":
+			t.Size = strings.TrimSpace(value.Text())
+			t.SizeBytes = parseSizeBytes(t.Size)
+		case "Completed":
+			t.Completed = parseCount(value)
+		case "Info hash":
+			t.InfoHash = strings.ToLower(strings.TrimSpace(value.Find("kbd").Text()))
+		}
+	})
+
+	doc.Find(".panel-footer a").Each(func(i int, link *goquery.Selection) {
+		if href, exists := link.Attr("href"); exists && strings.HasPrefix(href, "magnet:") {
+			t.Magnet = href
+		}
+	})
+	if t.InfoHash == "" {
+		t.InfoHash = magnet.InfoHash(t.Magnet)
+	}
+
+	detail.Description = strings.TrimSpace(doc.Find("#torrent-description").Text())
+	detail.Files = parseFileList(doc.Find(".torrent-file-list > ul"), "")
+	detail.CommentCount = parseCommentCount(doc)
+
+	return detail, nil
+}
+
+// parseFileList walks a nested file list, joining folder names into file paths
+func parseFileList(list *goquery.Selection, prefix string) []models.TorrentFile {
+	var files []models.TorrentFile
+	list.ChildrenFiltered("li").Each(func(i int, item *goquery.Selection) {
+		if folder := item.ChildrenFiltered("a.folder"); folder.Length() > 0 {
+			name := strings.TrimSpace(folder.Text())
+			files = append(files, parseFileList(item.ChildrenFiltered("ul"), path.Join(prefix, name))...)
+			return
+		}
+
+		size := strings.TrimSpace(item.ChildrenFiltered("span.file-size").Text())
+		entry := item.Clone()
+		entry.Find("span.file-size").Remove()
+		name := strings.TrimSpace(entry.Text())
+		if name == "" {
+			return
+		}
+		files = append(files, models.TorrentFile{
+			Path: path.Join(prefix, name),
+			Size: strings.TrimSuffix(strings.TrimPrefix(size, "("), ")"),
+		})
+	})
+	return files
+}
+
+// parseCommentCount reads the count from the "Comments - N" heading,
+// falling back to counting the rendered comments
+func parseCommentCount(doc *goquery.Document) int {
+	heading := strings.TrimSpace(doc.Find("#comments .panel-title").First().Text())
+	if matches := commentCountRegex.FindStringSubmatch(heading); len(matches) > 1 {
+		count, err := strconv.Atoi(matches[1])
+		if err == nil {
+			return count
+		}
+		log.Printf("Warning: failed to parse comment count %q: %v", heading, err)
+	}
+	return doc.Find(".comment-panel").Length()
+}
@@ -0,0 +1,196 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nyaa-crawler/pkg/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// detailHTML renders a Nyaa /view/{id} page modelled on the live layout
+func detailHTML(id int) string {
+	return fmt.Sprintf(`<html><body><div class="container">
+<div class="panel panel-success">
+	<div class="panel-heading"><h3 class="panel-title">[Group] Show - Batch (1080p)</h3></div>
+	<div class="panel-body">
+		<div class="row">
+			<div class="col-md-1">Category:</div>
+			<div class="col-md-5"><a href="/?c=1_0" title="Anime">Anime</a> - <a href="/?c=1_2" title="English-translated">English-translated</a></div>
+			<div class="col-md-1">Date:</div>
+			<div class="col-md-5" data-timestamp="1704110400">2024-01-01 12:00 UTC</div>
+		</div>
+		<div class="row">
+			<div class="col-md-1">Submitter:</div>
+			<div class="col-md-5"><a class="text-success" href="/user/uploader" title="Trusted">uploader</a></div>
+			<div class="col-md-1">Seeders:</div>
+			<div class="col-md-5"><span style="color: green;">321</span></div>
+		</div>
+		<div class="row">
+			<div class="col-md-1">Information:</div>
+			<div class="col-md-5"><a href="https://example.com/group">https://example.com/group</a></div>
+			<div class="col-md-1">Leechers:</div>
+			<div class="col-md-5"><span style="color: red;">12</span></div>
+		</div>
+		<div class="row">
+			<div class="col-md-1">File size:</div>
+			<div class="col-md-5">2.8 GiB</div>
+			<div class="col-md-1">Completed:</div>
+			<div class="col-md-5">4567</div>
+		</div>
+		<div class="row">
+			<div class="col-md-1">Info hash:</div>
+			<div class="col-md-5"><kbd>ABCDEF0123456789ABCDEF0123456789ABCDEF01</kbd></div>
+		</div>
+	</div>
+	<div class="panel-footer clearfix">
+		<a href="/download/%[1]d.torrent"><i class="fa fa-download fa-fw"></i>Download Torrent</a> or
+		<a href="magnet:?xt=urn:btih:abcdef0123456789abcdef0123456789abcdef01" class="card-footer-item"><i class="fa fa-magnet fa-fw"></i>Magnet</a>
+	</div>
+</div>
+<div class="panel panel-default">
+	<div markdown-text class="panel-body" id="torrent-description">Batch release of **Show**.</div>
+</div>
+<div class="panel panel-default">
+	<div class="panel-heading"><a class="accordion-toggle" data-toggle="collapse" href="#collapseFileList"><h3 class="panel-title">File list</h3></a></div>
+	<div class="collapse" id="collapseFileList">
+		<div class="torrent-file-list panel-body">
+			<ul>
+				<li><a href="" class="folder"><i class="fa fa-folder-open"></i>[Group] Show</a>
+					<ul>
+						<li><i class="fa fa-file"></i>Show - 01.mkv <span class="file-size">(1.4 GiB)</span></li>
+						<li><a href="" class="folder"><i class="fa fa-folder-open"></i>Extras</a>
+							<ul><li><i class="fa fa-file"></i>NCOP.mkv <span class="file-size">(120.0 MiB)</span></li></ul>
+						</li>
+					</ul>
+				</li>
+				<li><i class="fa fa-file"></i>readme.txt <span class="file-size">(1.0 KiB)</span></li>
+			</ul>
+		</div>
+	</div>
+</div>
+<div id="comments" class="panel panel-default">
+	<div class="panel-heading"><a class="accordion-toggle" data-toggle="collapse" href="#collapse-comments"><h3 class="panel-title">Comments - 2</h3></a></div>
+	<div class="collapse in" id="collapse-comments">
+		<div class="panel panel-default comment-panel"></div>
+		<div class="panel panel-default comment-panel"></div>
+	</div>
+</div>
+</div></body></html>`, id)
+}
+
+func TestParseTorrentDetail(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(detailHTML(1234)))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	detail, err := ParseTorrentDetail(doc, 1234)
+	if err != nil {
+		t.Fatalf("ParseTorrentDetail returned error: %v", err)
+	}
+
+	want := models.Torrent{
+		ID:          1234,
+		Name:        "[Group] Show - Batch (1080p)",
+		Magnet:      "magnet:?xt=urn:btih:abcdef0123456789abcdef0123456789abcdef01",
+		Category:    "Anime - English-translated",
+		CategoryID:  "1_2",
+		Size:        "2.8 GiB",
+		Date:        "2024-01-01 12:00",
+		SizeBytes:   3006477107,
+		PublishedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Seeders:     321,
+		Leechers:    12,
+		Completed:   4567,
+		InfoHash:    "abcdef0123456789abcdef0123456789abcdef01",
+		Trusted:     true,
+	}
+	if detail.Torrent != want {
+		t.Errorf("Torrent = %+v, want %+v", detail.Torrent, want)
+	}
+	if detail.Submitter != "uploader" || detail.InformationURL != "https://example.com/group" {
+		t.Errorf("unexpected submitter/information: %q %q", detail.Submitter, detail.InformationURL)
+	}
+	if detail.Description != "Batch release of **Show**." {
+		t.Errorf("unexpected description: %q", detail.Description)
+	}
+	if detail.CommentCount != 2 {
+		t.Errorf("expected 2 comments, got %d", detail.CommentCount)
+	}
+
+	wantFiles := []models.TorrentFile{
+		{Path: "[Group] Show/Show - 01.mkv", Size: "1.4 GiB"},
+		{Path: "[Group] Show/Extras/NCOP.mkv", Size: "120.0 MiB"},
+		{Path: "readme.txt", Size: "1.0 KiB"},
+	}
+	if len(detail.Files) != len(wantFiles) {
+		t.Fatalf("expected %d files, got %+v", len(wantFiles), detail.Files)
+	}
+	for i := range wantFiles {
+		if detail.Files[i] != wantFiles[i] {
+			t.Errorf("file %d = %+v, want %+v", i, detail.Files[i], wantFiles[i])
+		}
+	}
+}
+
+func TestParseTorrentDetailMissingTitle(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body><p>Not found</p></body></html>"))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	if _, err := ParseTorrentDetail(doc, 1); err == nil {
+		t.Error("expected error for page without a title")
+	}
+}
+
+// mockDetailInserter adds detail storage to mockTorrentInserter
+type mockDetailInserter struct {
+	mockTorrentInserter
+	Details []models.TorrentDetail
+}
+
+func (m *mockDetailInserter) SaveTorrentDetail(detail models.TorrentDetail) error {
+	m.Details = append(m.Details, detail)
+	return nil
+}
+
+func TestScrapeDetail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/view/1234" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprint(w, detailHTML(1234))
+	}))
+	defer server.Close()
+
+	mockDB := &mockDetailInserter{}
+	c, err := NewCrawler(WithDB(mockDB), WithBaseURL(server.URL+"/?q=ignored"))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	detail, err := c.ScrapeDetail(context.Background(), 1234)
+	if err != nil {
+		t.Fatalf("ScrapeDetail returned error: %v", err)
+	}
+	if len(detail.Files) != 3 {
+		t.Errorf("expected 3 files, got %d", len(detail.Files))
+	}
+	if len(mockDB.Torrents) != 1 || len(mockDB.Details) != 1 {
+		t.Errorf("expected torrent and detail to be stored, got %d/%d", len(mockDB.Torrents), len(mockDB.Details))
+	}
+}
+
+func TestWithBaseURLRequiresAbsoluteURL(t *testing.T) {
+	if _, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithBaseURL("/relative")); err == nil {
+		t.Error("expected error for relative base URL")
+	}
+}
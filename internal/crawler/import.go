@@ -0,0 +1,384 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"nyaa-crawler/pkg/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// downloadIDRegex extracts the torrent ID from the download link of a detail page
+var downloadIDRegex = regexp.MustCompile(`/download/(\d+)\.torrent`)
+
+// errUnrecognizedPage is returned for saved pages that are neither a listing
+// nor a detail page, such as other pages captured in a WARC archive
+var errUnrecognizedPage = errors.New("not a listing or detail page")
+
+// importExts are the file types Import reads
+var importExts = []string{".html", ".htm", ".xml", ".gz", ".warc"}
+
+// ImportOptions configures an Import run
+type ImportOptions struct {
+	// Workers is the number of files parsed in parallel (default 4)
+	Workers int
+	// BatchSize is the number of torrents collected before a database insert (default 500)
+	BatchSize int
+}
+
+// ImportResult summarizes an Import run
+type ImportResult struct {
+	Files    int // files read
+	Pages    int // pages parsed from them
+	Skipped  int // pages of another site, error responses and unrecognized pages
+	Failed   int // files and pages that could not be parsed
+	Found    int
+	Inserted int
+}
+
+// parsedPage holds the rows parsed from a saved page
+type parsedPage struct {
+	torrents []models.Torrent
+	details  []models.TorrentDetail
+}
+
+// errImportStopped ends reading a file once the import is cancelled
+var errImportStopped = errors.New("import stopped")
+
+// importedFile reports progress on one file. Pages are sent one at a time as
+// they are parsed, so large archives are never held in memory; the last
+// message of a file has done set.
+type importedFile struct {
+	path    string
+	page    *parsedPage
+	skipped int
+	failed  int
+	done    bool
+	err     error
+}
+
+// Import parses saved listing and detail pages under root (a file or a
+// directory) and stores the torrents that are not stored yet, keeping the
+// swarm counts of stored ones like Reparse. It reads HTML and RSS files, gzip
+// files including snapshots from WithSnapshotDir, and WARC archives
+// (.warc, .warc.gz). Files are parsed in parallel; rows are inserted in
+// batches from a single goroutine.
+func (c *Crawler) Import(ctx context.Context, root string, opts ImportOptions) (*ImportResult, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	paths, err := importFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan string)
+	files := make(chan importedFile)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			send := func(file importedFile) bool {
+				select {
+				case files <- file:
+					return true
+				case <-workCtx.Done():
+					return false
+				}
+			}
+			for path := range jobs {
+				if !c.importFile(path, send) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, path := range paths {
+			select {
+			case jobs <- path:
+			case <-workCtx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(files)
+	}()
+
+	result := &ImportResult{}
+	var torrents []models.Torrent
+	var details []models.TorrentDetail
+	flush := func() error {
+		if len(torrents) == 0 {
+			return nil
+		}
+		page, err := c.storeArchivedRows(torrents, details)
+		if err != nil {
+			return err
+		}
+		result.Inserted += page.Inserted
+		torrents, details = nil, nil
+		return nil
+	}
+
+	for file := range files {
+		result.Skipped += file.skipped
+		result.Failed += file.failed
+		if page := file.page; page != nil {
+			result.Pages++
+			result.Found += len(page.torrents)
+			torrents = append(torrents, page.torrents...)
+			details = append(details, page.details...)
+			if len(torrents) >= opts.BatchSize {
+				if err := flush(); err != nil {
+					return result, err
+				}
+			}
+		}
+		if !file.done {
+			continue
+		}
+		if file.err != nil {
+			log.Printf("Failed to import %s: %v", file.path, file.err)
+			result.Failed++
+			continue
+		}
+		result.Files++
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, ctx.Err()
+}
+
+// importFiles lists the importable files under root in name order
+func importFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+
+	var paths []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+		for _, supported := range importExts {
+			if ext == supported {
+				paths = append(paths, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %w", root, err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// importFile reads and parses every page of a file, passing each to send as
+// soon as it is parsed. It returns false when send reports that the import
+// was stopped.
+func (c *Crawler) importFile(path string, send func(importedFile) bool) bool {
+	err := readPages(path, func(s *Snapshot) error {
+		file := importedFile{path: path}
+		if (s.Status != 0 && s.Status != http.StatusOK) || (s.Site != "" && s.Site != c.site.Name) {
+			file.skipped++
+		} else if page, err := c.parsePage(s); errors.Is(err, errUnrecognizedPage) {
+			file.skipped++
+		} else if err != nil {
+			name := s.URL
+			if name == "" {
+				name = path
+			}
+			log.Printf("Failed to parse %s: %v", name, err)
+			file.failed++
+		} else {
+			file.page = page
+		}
+		if !send(file) {
+			return errImportStopped
+		}
+		return nil
+	})
+	if errors.Is(err, errImportStopped) {
+		return false
+	}
+	return send(importedFile{path: path, done: true, err: err})
+}
+
+// readPages calls fn for each saved page of a file. Plain HTML and RSS files
+// carry no metadata, so their kind is left to parsePage.
+func readPages(path string, fn func(*Snapshot) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	name := strings.ToLower(path)
+	var r io.Reader = f
+	var comment string
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = zr.Close() }()
+		r = zr
+		comment = zr.Comment
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	if strings.HasSuffix(name, ".warc") {
+		return readWARCPages(r, fn)
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s := &Snapshot{Body: body}
+	if comment != "" {
+		if err := json.Unmarshal([]byte(comment), s); err != nil {
+			return fmt.Errorf("invalid snapshot metadata: %w", err)
+		}
+	} else if strings.HasSuffix(name, ".xml") {
+		s.Kind = SnapshotRSS
+	}
+	return fn(s)
+}
+
+// readWARCPages calls fn for each HTML and RSS response stored in a WARC archive
+func readWARCPages(r io.Reader, fn func(*Snapshot) error) error {
+	return readWARC(r, func(record warcRecord) error {
+		if record.Type != "response" || !strings.HasPrefix(record.TargetURI, "http") {
+			return nil
+		}
+		status, body, err := warcResponse(record)
+		if err != nil {
+			log.Printf("Skipping WARC record: %v", err)
+			return nil
+		}
+		return fn(&Snapshot{URL: record.TargetURI, Status: status, Body: body})
+	})
+}
+
+// parsePage parses a saved page. Pages of unknown kind are recognized by their
+// URL, or by their content when the URL is unknown too.
+func (c *Crawler) parsePage(s *Snapshot) (*parsedPage, error) {
+	kind := s.Kind
+	if kind == "" && s.URL != "" {
+		kind = c.kindFromURL(s.URL)
+	}
+	if kind == SnapshotRSS {
+		torrents, err := c.site.ParseRSS(bytes.NewReader(s.Body))
+		if err != nil {
+			return nil, err
+		}
+		return &parsedPage{torrents: torrents}, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(s.Body))
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case SnapshotListing:
+		return &parsedPage{torrents: c.site.ParseTorrents(doc)}, nil
+	case SnapshotDetail:
+		return c.parseDetailPage(s.URL, doc)
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown page kind %q", kind)
+	}
+
+	// A listing has torrent rows, a detail page a download link
+	if torrents := c.site.ParseTorrents(doc); len(torrents) > 0 {
+		return &parsedPage{torrents: torrents}, nil
+	}
+	if _, ok := c.detailID(s.URL, doc); ok {
+		return c.parseDetailPage(s.URL, doc)
+	}
+	return nil, errUnrecognizedPage
+}
+
+// parseDetailPage parses a saved detail page
+func (c *Crawler) parseDetailPage(pageURL string, doc *goquery.Document) (*parsedPage, error) {
+	id, ok := c.detailID(pageURL, doc)
+	if !ok {
+		return nil, fmt.Errorf("no torrent ID in detail page %s", pageURL)
+	}
+	detail, err := c.parseDetail(doc, id)
+	if err != nil {
+		return nil, err
+	}
+	return &parsedPage{
+		torrents: []models.Torrent{detail.Torrent},
+		details:  []models.TorrentDetail{*detail},
+	}, nil
+}
+
+// detailID returns the torrent ID of a detail page from its URL, or from its
+// download link when the URL is unknown
+func (c *Crawler) detailID(pageURL string, doc *goquery.Document) (int, bool) {
+	matches := c.site.IDPattern.FindStringSubmatch(pageURL)
+	if matches == nil {
+		href, _ := doc.Find(`.panel-footer a[href*="/download/"]`).First().Attr("href")
+		matches = downloadIDRegex.FindStringSubmatch(href)
+	}
+	if matches == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(matches[1])
+	return id, err == nil
+}
+
+// kindFromURL recognizes detail pages and RSS feeds by their URL
+func (c *Crawler) kindFromURL(pageURL string) SnapshotKind {
+	if c.site.IDPattern.MatchString(pageURL) {
+		return SnapshotDetail
+	}
+	if u, err := url.Parse(pageURL); err == nil && u.Query().Get("page") == "rss" {
+		return SnapshotRSS
+	}
+	return ""
+}
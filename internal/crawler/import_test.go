@@ -0,0 +1,184 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nyaa-crawler/pkg/models"
+)
+
+// warcRecordBytes builds a WARC/1.0 record as a gzip member, the way
+// .warc.gz archives store each record
+func warcRecordBytes(t *testing.T, warcType, targetURI, block string) []byte {
+	t.Helper()
+	var record bytes.Buffer
+	fmt.Fprintf(&record, "WARC/1.0\r\nWARC-Type: %s\r\nWARC-Target-URI: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		warcType, targetURI, len(block), block)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(record.Bytes()); err != nil {
+		t.Fatalf("Failed to compress WARC record: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to compress WARC record: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// httpResponse is the raw HTTP response block of a WARC response record
+func httpResponse(status int, body string) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s\r\nContent-Type: text/html\r\nContent-Length: %d\r\n\r\n%s",
+		status, http.StatusText(status), len(body), body)
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestImportDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "listing.html"), []byte(listingHTML(3, 2, 1)))
+	writeFile(t, filepath.Join(dir, "pages", "detail.htm"), []byte(detailHTML(7)))
+	writeFile(t, filepath.Join(dir, "pages", "about.html"), []byte("<html><body><h1>About</h1></body></html>"))
+	writeFile(t, filepath.Join(dir, "broken.gz"), []byte("not gzip"))
+	writeFile(t, filepath.Join(dir, "notes.txt"), []byte("ignored"))
+	if _, err := WriteSnapshot(dir, &Snapshot{URL: "https://nyaa.si/?p=2", Site: "nyaa", Kind: SnapshotListing,
+		Status: http.StatusOK, FetchedAt: time.Now(), Body: []byte(listingHTML(5, 4))}); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+
+	var warc []byte
+	warc = append(warc, warcRecordBytes(t, "warcinfo", "", "software: test")...)
+	warc = append(warc, warcRecordBytes(t, "request", "https://nyaa.si/", "GET / HTTP/1.1\r\n\r\n")...)
+	warc = append(warc, warcRecordBytes(t, "response", "https://nyaa.si/?p=3", httpResponse(200, listingHTML(6)))...)
+	warc = append(warc, warcRecordBytes(t, "response", "<https://nyaa.si/view/8>", httpResponse(200, detailHTML(8)))...)
+	warc = append(warc, warcRecordBytes(t, "response", "https://nyaa.si/view/9", httpResponse(404, "Not Found"))...)
+	writeFile(t, filepath.Join(dir, "crawl.warc.gz"), warc)
+
+	mockDB := &mockTorrentInserter{}
+	c, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	result, err := c.Import(context.Background(), dir, ImportOptions{Workers: 3, BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	want := ImportResult{Files: 5, Pages: 5, Skipped: 2, Failed: 1, Found: 8, Inserted: 8}
+	if *result != want {
+		t.Errorf("expected %+v, got %+v", want, *result)
+	}
+	for id := 1; id <= 8; id++ {
+		if !mockDB.has(id) {
+			t.Errorf("expected torrent %d to be imported", id)
+		}
+	}
+}
+
+func TestImportSingleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detail.html")
+	writeFile(t, path, []byte(detailHTML(42)))
+
+	mockDB := &mockTorrentInserter{}
+	c, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	result, err := c.Import(context.Background(), path, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Files != 1 || result.Inserted != 1 || !mockDB.has(42) {
+		t.Errorf("expected torrent 42 from the detail page's download link, got %+v", result)
+	}
+
+	if _, err := c.Import(context.Background(), filepath.Join(t.TempDir(), "missing"), ImportOptions{}); err == nil {
+		t.Error("expected error for a missing path")
+	}
+}
+
+func TestImportKeepsLiveStats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "old.html"), []byte(listingHTML(3, 2)))
+	writeFile(t, filepath.Join(dir, "old.warc.gz"),
+		warcRecordBytes(t, "response", "https://nyaa.si/view/8", httpResponse(200, detailHTML(8))))
+
+	store := &mockLiveStore{}
+	store.Torrents = []models.Torrent{{Site: "nyaa", ID: 2, Name: "Torrent 2", Seeders: 99, Leechers: 9, Completed: 999}}
+	c, err := NewCrawler(WithDB(store))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	result, err := c.Import(context.Background(), dir, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Inserted != 2 || !store.has(3) || !store.has(8) {
+		t.Errorf("expected the missing torrents 3 and 8 to be inserted, got %+v", result)
+	}
+	if _, ok := store.details[8]; !ok {
+		t.Error("expected the detail of torrent 8 to be stored")
+	}
+	if live := store.Torrents[store.index(2)]; live.Seeders != 99 || live.Leechers != 9 || live.Completed != 999 {
+		t.Errorf("expected the newer counts of torrent 2 to be kept, got %+v", live)
+	}
+	if store.statsRows != 0 {
+		t.Errorf("expected no swarm history from imported pages, got %d rows", store.statsRows)
+	}
+}
+
+func TestImportOversizedWARCRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrupt.warc")
+	page := httpResponse(200, listingHTML(6))
+	warc := fmt.Sprintf("WARC/1.0\r\nWARC-Type: response\r\nWARC-Target-URI: https://nyaa.si/\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n", len(page), page) +
+		"WARC/1.0\r\nWARC-Type: response\r\nWARC-Target-URI: https://nyaa.si/?p=2\r\nContent-Length: 9223372036854775807\r\n\r\ntruncated"
+	writeFile(t, path, []byte(warc))
+
+	mockDB := &mockTorrentInserter{}
+	c, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	result, err := c.Import(context.Background(), path, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	// The page before the corrupt record is kept; the file counts as failed
+	if result.Pages != 1 || result.Failed != 1 || result.Files != 0 || !mockDB.has(6) {
+		t.Errorf("expected the first page to be imported and the file to fail, got %+v", result)
+	}
+}
+
+func TestImportCancelled(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 20; i++ {
+		writeFile(t, filepath.Join(dir, fmt.Sprintf("page%02d.html", i)), []byte(listingHTML(i)))
+	}
+
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Import(ctx, dir, ImportOptions{Workers: 2}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"nyaa-crawler/pkg/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ErrLayoutChanged is returned when a listing page no longer looks like the
+// layout the parser was written for, so a markup change on the site does not
+// pass as an empty page
+var ErrLayoutChanged = errors.New("listing layout changed")
+
+// Layout describes the structure of a listing page the parser expects
+type Layout struct {
+	// Table selects the listing table
+	Table string
+	// Headers are the classes of the table's header cells, in column order.
+	// Other header cells may sit between them, like the comments column that
+	// shares the name cell's colspan on Nyaa.
+	Headers []string
+	// NoResults is the text shown instead of the table when nothing matches
+	NoResults string
+}
+
+// nyaaLayout matches the listing pages of nyaa.si and sukebei.nyaa.si
+var nyaaLayout = Layout{
+	Table: "table.torrent-list",
+	Headers: []string{
+		"hdr-category", "hdr-name", "hdr-link", "hdr-size",
+		"hdr-date", "hdr-seeders", "hdr-leechers", "hdr-downloads",
+	},
+	NoResults: "No results found",
+}
+
+// LayoutReport describes how well a listing page matched the site's layout
+type LayoutReport struct {
+	Rows      int // rows matched by the row selector
+	Malformed int // rows without an ID, name or magnet link
+	// MissingHeaders are the expected header classes not found in column order
+	MissingHeaders []string
+	NoTable        bool // the listing table is missing
+	NoResults      bool // the page says that nothing matched
+}
+
+// LayoutError reports why a listing page failed the layout check
+type LayoutError struct {
+	Reason string
+	Report LayoutReport
+}
+
+func (e *LayoutError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrLayoutChanged, e.Reason)
+}
+
+// Is lets errors.Is match LayoutError against ErrLayoutChanged
+func (e *LayoutError) Is(target error) bool {
+	return target == ErrLayoutChanged
+}
+
+// WithLayoutCheck fails listing scrapes with a LayoutError when the page lacks
+// the listing table or its expected column headers, or when more than
+// maxMalformed (a fraction, 0 to 1) of the rows fail to parse. Pages within
+// the threshold are stored without their malformed rows.
+func WithLayoutCheck(maxMalformed float64) Option {
+	return func(c *Crawler) error {
+		if maxMalformed < 0 || maxMalformed > 1 {
+			return fmt.Errorf("malformed row threshold must be between 0 and 1, got %v", maxMalformed)
+		}
+		c.layoutCheck = true
+		c.maxMalformed = maxMalformed
+		return nil
+	}
+}
+
+// ParseListing parses a listing page like ParseTorrents and reports how well
+// the page matched the site's layout. Rows without an ID are dropped; rows
+// without a name or magnet link are returned but counted as malformed.
+func (s Site) ParseListing(doc *goquery.Document) ([]models.Torrent, LayoutReport) {
+	var report LayoutReport
+	if s.Layout.Table != "" {
+		table := doc.Find(s.Layout.Table).First()
+		if table.Length() == 0 {
+			report.NoTable = true
+			report.NoResults = s.Layout.NoResults != "" && strings.Contains(doc.Text(), s.Layout.NoResults)
+		} else {
+			report.MissingHeaders = missingHeaders(table.Find("thead th"), s.Layout.Headers)
+		}
+	}
+
+	var torrents []models.Torrent
+	doc.Find(s.Selectors.Row).Each(func(i int, row *goquery.Selection) {
+		report.Rows++
+		torrent := s.ParseTorrentRow(row)
+		if torrent == nil {
+			report.Malformed++
+			return
+		}
+		if incomplete(*torrent) {
+			report.Malformed++
+		}
+		torrents = append(torrents, *torrent)
+	})
+
+	return torrents, report
+}
+
+// incomplete reports whether a parsed row lacks its name or magnet link
+func incomplete(t models.Torrent) bool {
+	return t.Name == "" || t.Magnet == ""
+}
+
+// completeTorrents drops the rows that lack their name or magnet link
+func completeTorrents(torrents []models.Torrent) []models.Torrent {
+	complete := torrents[:0]
+	for _, t := range torrents {
+		if !incomplete(t) {
+			complete = append(complete, t)
+		}
+	}
+	return complete
+}
+
+// missingHeaders returns the classes that are not found among headers in the
+// given order. Header cells without an expected class are skipped.
+func missingHeaders(headers *goquery.Selection, classes []string) []string {
+	var missing []string
+	next := 0
+	for _, class := range classes {
+		found := false
+		for i := next; i < headers.Length(); i++ {
+			if headers.Eq(i).HasClass(class) {
+				next = i + 1
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, class)
+		}
+	}
+	return missing
+}
+
+// checkLayout returns a LayoutError when report exceeds the crawler's thresholds
+func (c *Crawler) checkLayout(report LayoutReport) error {
+	if !c.layoutCheck {
+		return nil
+	}
+	switch {
+	case report.NoTable && !report.NoResults:
+		return &LayoutError{Reason: fmt.Sprintf("listing table %q not found", c.site.Layout.Table), Report: report}
+	case len(report.MissingHeaders) > 0:
+		return &LayoutError{Reason: "missing column headers " + strings.Join(report.MissingHeaders, ", "), Report: report}
+	case report.Rows > 0 && float64(report.Malformed)/float64(report.Rows) > c.maxMalformed:
+		return &LayoutError{Reason: fmt.Sprintf("%d of %d rows failed to parse", report.Malformed, report.Rows), Report: report}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// newHTMLServer serves the same page for every request
+func newHTMLServer(t *testing.T, page string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// brokenRow is a listing row whose title link lost its /view/ID href
+const brokenRow = `<tr class="default"><td><a href="/?c=1_2" title="Anime"></a></td>
+<td colspan="2"><a href="/torrents/abc">Renamed markup</a></td><td></td></tr>`
+
+func scrapeWithLayoutCheck(t *testing.T, page string, maxMalformed float64) ([]PageResult, error) {
+	t.Helper()
+	server := newHTMLServer(t, page)
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithLayoutCheck(maxMalformed))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	return c.ScrapePages(context.Background(), server.URL, 1)
+}
+
+func TestParseListingReport(t *testing.T) {
+	page := strings.Replace(listingHTML(3, 2, 1), "</tbody>", brokenRow+"</tbody>", 1)
+	// A row that still links to its view page but lost its magnet is returned,
+	// and counted as malformed
+	page = strings.Replace(page, fmt.Sprintf(`href="magnet:?xt=urn:btih:%040d"`, 2), `href="#"`, 1)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	torrents, report := Nyaa.ParseListing(doc)
+	if len(torrents) != 3 || torrents[1].ID != 2 || torrents[1].Magnet != "" {
+		t.Errorf("expected torrents 3, 2 without a magnet and 1, got %+v", torrents)
+	}
+	if report.Rows != 4 || report.Malformed != 2 || report.NoTable || len(report.MissingHeaders) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestParseListingRealPage(t *testing.T) {
+	f, err := os.Open("testdata/nyaa_listing.html")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	torrents, report := Nyaa.ParseListing(doc)
+	if report.Rows != 2 || report.Malformed != 0 || report.NoTable || len(report.MissingHeaders) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(torrents) != 2 {
+		t.Fatalf("expected 2 torrents, got %d", len(torrents))
+	}
+	first := torrents[0]
+	if first.ID != 1755393 || first.Name != "[SubsPlease] Example Show - 01 (1080p) [A1B2C3D4].mkv" ||
+		first.InfoHash != "0123456789abcdef0123456789abcdef01234567" || first.CategoryID != "1_2" ||
+		first.Size != "1.4 GiB" || first.Seeders != 1234 || first.Leechers != 56 || first.Completed != 7890 || !first.Trusted {
+		t.Errorf("unexpected first torrent %+v", first)
+	}
+	if second := torrents[1]; second.ID != 1755392 || second.Name != "Example Novel Vol. 3 (EPUB)" || second.Trusted {
+		t.Errorf("unexpected second torrent %+v", second)
+	}
+}
+
+func TestParseListingHeaderOrder(t *testing.T) {
+	// Swapping two expected columns is a layout change even though every
+	// class is still present
+	page := strings.Replace(listingHTML(1), `<th class="hdr-size">Size</th><th class="hdr-date">Date</th>`,
+		`<th class="hdr-date">Date</th><th class="hdr-size">Size</th>`, 1)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	if _, report := Nyaa.ParseListing(doc); len(report.MissingHeaders) != 1 || report.MissingHeaders[0] != "hdr-date" {
+		t.Errorf("expected hdr-date to be reported out of order, got %v", report.MissingHeaders)
+	}
+}
+
+func TestLayoutCheckMissingHeaders(t *testing.T) {
+	page := strings.Replace(listingHTML(2, 1), "hdr-seeders", "hdr-peers", 1)
+	_, err := scrapeWithLayoutCheck(t, page, 0.2)
+	if !errors.Is(err, ErrLayoutChanged) {
+		t.Fatalf("expected ErrLayoutChanged, got %v", err)
+	}
+	var layoutErr *LayoutError
+	if !errors.As(err, &layoutErr) || len(layoutErr.Report.MissingHeaders) != 1 || layoutErr.Report.MissingHeaders[0] != "hdr-seeders" {
+		t.Errorf("expected hdr-seeders to be reported missing, got %v", err)
+	}
+}
+
+func TestLayoutCheckMissingTable(t *testing.T) {
+	if _, err := scrapeWithLayoutCheck(t, "<html><body><div class=\"results\"></div></body></html>", 0.2); !errors.Is(err, ErrLayoutChanged) {
+		t.Errorf("expected ErrLayoutChanged for a page without the table, got %v", err)
+	}
+
+	// A search without results has no table either, but says so
+	results, err := scrapeWithLayoutCheck(t, "<html><body><h3>No results found</h3></body></html>", 0.2)
+	if err != nil {
+		t.Fatalf("expected an empty search to pass, got %v", err)
+	}
+	if len(results) != 1 || results[0].Found != 0 {
+		t.Errorf("expected a single empty page, got %+v", results)
+	}
+
+	// So does the end of pagination
+	if _, err := scrapeWithLayoutCheck(t, listingHTML(), 0.2); err != nil {
+		t.Errorf("expected an empty listing table to pass, got %v", err)
+	}
+}
+
+func TestLayoutCheckMalformedRows(t *testing.T) {
+	page := strings.Replace(listingHTML(3, 2, 1), "</tbody>", brokenRow+"</tbody>", 1)
+	results, err := scrapeWithLayoutCheck(t, page, 0.5)
+	if err != nil {
+		t.Fatalf("expected 1 bad row in 4 to pass a 50%% threshold, got %v", err)
+	}
+	if results[0].Found != 3 || results[0].Malformed != 1 {
+		t.Errorf("expected 3 found and 1 malformed, got %+v", results[0])
+	}
+
+	// Rows without a magnet link are not stored when the layout is checked
+	page = strings.Replace(listingHTML(3, 2, 1), fmt.Sprintf(`href="magnet:?xt=urn:btih:%040d"`, 2), `href="#"`, 1)
+	results, err = scrapeWithLayoutCheck(t, page, 0.5)
+	if err != nil {
+		t.Fatalf("expected 1 bad row in 3 to pass a 50%% threshold, got %v", err)
+	}
+	if results[0].Found != 2 || results[0].Malformed != 1 {
+		t.Errorf("expected the row without a magnet to be dropped, got %+v", results[0])
+	}
+
+	page = strings.Replace(listingHTML(1), "</tbody>", brokenRow+brokenRow+"</tbody>", 1)
+	if _, err := scrapeWithLayoutCheck(t, page, 0.5); !errors.Is(err, ErrLayoutChanged) {
+		t.Errorf("expected ErrLayoutChanged for 2 bad rows in 3, got %v", err)
+	}
+}
+
+func TestLayoutCheckIsOptIn(t *testing.T) {
+	server := newHTMLServer(t, "<html><body>maintenance</body></html>")
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.ScrapePages(context.Background(), server.URL, 1); err != nil {
+		t.Errorf("expected no layout check without WithLayoutCheck, got %v", err)
+	}
+
+	if _, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithLayoutCheck(1.5)); err == nil {
+		t.Error("expected error for a threshold above 1")
+	}
+}
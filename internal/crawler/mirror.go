@@ -0,0 +1,205 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// challengeSnippetSize is how much of an error page is read to recognize a
+// Cloudflare challenge
+const challengeSnippetSize = 64 << 10
+
+// challengeMarkers appear in Cloudflare challenge and interstitial pages
+var challengeMarkers = [][]byte{
+	[]byte("challenge-platform"),
+	[]byte("cf-chl-"),
+	[]byte("<title>Just a moment...</title>"),
+	[]byte("<title>Attention Required! | Cloudflare</title>"),
+}
+
+// isChallenge reports whether a non-200 response is a Cloudflare challenge
+// rather than an answer from the site itself
+func isChallenge(resp *http.Response, body []byte) bool {
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Server")), "cloudflare") {
+		return false
+	}
+	for _, marker := range challengeMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchResult is a fetched page and the mirror that served it
+type fetchResult struct {
+	Body   []byte
+	URL    string
+	Mirror string
+	// Validators holds the page's cache validators for a conditional fetch,
+	// to be stored once the page has been processed
+	Validators *validators
+}
+
+// WithMirrors sets an ordered list of base URLs serving the same site. The
+// first is the primary and replaces the base URL; when a mirror fails with a
+// connection error or a Cloudflare challenge, or its circuit breaker is open,
+// the request is repeated on the next one. Apply it after WithSite and WithBaseURL, which reset the list.
+func WithMirrors(baseURLs ...string) Option {
+	return func(c *Crawler) error {
+		if len(baseURLs) == 0 {
+			return fmt.Errorf("at least one mirror is required")
+		}
+		mirrors := make([]*url.URL, 0, len(baseURLs))
+		for _, raw := range baseURLs {
+			u, err := parseBaseURL(raw)
+			if err != nil {
+				return fmt.Errorf("invalid mirror: %w", err)
+			}
+			mirrors = append(mirrors, u)
+		}
+		c.baseURL = mirrors[0]
+		c.mirrors = mirrors
+		c.setActiveMirror(0)
+		return nil
+	}
+}
+
+// Mirrors returns the configured base URLs in failover order
+func (c *Crawler) Mirrors() []string {
+	names := make([]string, len(c.mirrors))
+	for i, m := range c.mirrors {
+		names[i] = m.String()
+	}
+	return names
+}
+
+// parseBaseURL parses an absolute site root URL
+func parseBaseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("base URL must be absolute: %q", raw)
+	}
+	return u, nil
+}
+
+// mirrorPath returns the path prefix of a mirror, ending in a slash
+func mirrorPath(u *url.URL) string {
+	if strings.HasSuffix(u.Path, "/") {
+		return u.Path
+	}
+	return u.Path + "/"
+}
+
+// matchMirror returns the index of the mirror targetURL belongs to, or -1
+func (c *Crawler) matchMirror(target *url.URL) int {
+	path := target.Path
+	if path == "" {
+		path = "/"
+	}
+	for i, m := range c.mirrors {
+		if strings.EqualFold(target.Scheme, m.Scheme) && strings.EqualFold(target.Host, m.Host) &&
+			strings.HasPrefix(path, mirrorPath(m)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// rebase moves target from one mirror to another, keeping the path below
+// the mirror root and the query
+func rebase(target, from, to *url.URL) string {
+	path := target.Path
+	if path == "" {
+		path = "/"
+	}
+	u := *target
+	u.Scheme = to.Scheme
+	u.Host = to.Host
+	u.User = to.User
+	u.Path = mirrorPath(to) + strings.TrimPrefix(path, mirrorPath(from))
+	u.RawPath = ""
+	return u.String()
+}
+
+func (c *Crawler) activeMirrorIndex() int {
+	c.mirrorMu.Lock()
+	defer c.mirrorMu.Unlock()
+	return c.activeMirror
+}
+
+func (c *Crawler) setActiveMirror(i int) {
+	c.mirrorMu.Lock()
+	defer c.mirrorMu.Unlock()
+	c.activeMirror = i
+}
+
+// shouldFailover reports whether another mirror might succeed where this one
+// failed. Each mirror has its own circuit breaker, so one whose breaker is
+// open is skipped too.
+func shouldFailover(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrChallenge) || errors.Is(err, ErrCircuitOpen)
+}
+
+// fetch retrieves targetURL, failing over across mirrors when the URL belongs
+// to one of them. It starts with the mirror that served the last successful
+// request, so a dead primary is not retried for every page. Conditional
+// fetches may return ErrNotModified (see request).
+func (c *Crawler) fetch(ctx context.Context, targetURL string, conditional bool) (*fetchResult, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	from := c.matchMirror(target)
+	if from < 0 || len(c.mirrors) == 1 {
+		body, fresh, err := c.request(ctx, targetURL, conditional)
+		if err != nil {
+			return nil, err
+		}
+		mirror := (&url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/"}).String()
+		if from >= 0 {
+			mirror = c.mirrors[from].String()
+		}
+		return &fetchResult{Body: body, URL: targetURL, Mirror: mirror, Validators: fresh}, nil
+	}
+
+	start := c.activeMirrorIndex()
+	var lastErr error
+	for n := 0; n < len(c.mirrors); n++ {
+		i := (start + n) % len(c.mirrors)
+		mirror := c.mirrors[i]
+		mirrorURL := rebase(target, c.mirrors[from], mirror)
+
+		body, fresh, err := c.request(ctx, mirrorURL, conditional)
+		if err == nil {
+			if i != start {
+				log.Printf("Switched to mirror %s", mirror)
+				c.setActiveMirror(i)
+			}
+			return &fetchResult{Body: body, URL: mirrorURL, Mirror: mirror.String(), Validators: fresh}, nil
+		}
+		lastErr = err
+		if !shouldFailover(err) {
+			break
+		}
+		if n < len(c.mirrors)-1 {
+			log.Printf("Mirror %s failed (%v), failing over", mirror, err)
+		}
+	}
+	return nil, lastErr
+}
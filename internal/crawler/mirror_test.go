@@ -0,0 +1,203 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRebase(t *testing.T) {
+	nyaa, _ := url.Parse("https://nyaa.si/")
+	prefixed, _ := url.Parse("https://mirror.example/nyaa")
+
+	tests := []struct {
+		target   string
+		from, to *url.URL
+		want     string
+	}{
+		{"https://nyaa.si/?p=2&q=foo", nyaa, prefixed, "https://mirror.example/nyaa/?p=2&q=foo"},
+		{"https://nyaa.si/view/5", nyaa, prefixed, "https://mirror.example/nyaa/view/5"},
+		{"https://nyaa.si", nyaa, prefixed, "https://mirror.example/nyaa/"},
+		{"https://mirror.example/nyaa/view/5", prefixed, nyaa, "https://nyaa.si/view/5"},
+	}
+
+	for _, tt := range tests {
+		target, _ := url.Parse(tt.target)
+		if got := rebase(target, tt.from, tt.to); got != tt.want {
+			t.Errorf("rebase(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestIsChallenge(t *testing.T) {
+	challengeBody := []byte(`<html><head><title>Just a moment...</title></head><body><script src="/cdn-cgi/challenge-platform/x.js"></script></body></html>`)
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   []byte
+		want   bool
+	}{
+		{"mitigated header", 403, http.Header{"Cf-Mitigated": {"challenge"}}, nil, true},
+		{"cloudflare interstitial", 503, http.Header{"Server": {"cloudflare"}}, challengeBody, true},
+		{"cloudflare plain error", 503, http.Header{"Server": {"cloudflare"}}, []byte("Service Unavailable"), false},
+		{"origin 403", 403, http.Header{"Server": {"nginx"}}, challengeBody, false},
+		{"not found", 404, http.Header{"Server": {"cloudflare"}}, challengeBody, false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: tt.header}
+		if got := isChallenge(resp, tt.body); got != tt.want {
+			t.Errorf("%s: isChallenge = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newChallengeServer answers every request with a Cloudflare challenge page
+func newChallengeServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Server", "cloudflare")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<html><head><title>Just a moment...</title></head></html>`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestFailoverOnChallenge(t *testing.T) {
+	primary, primaryHits := newChallengeServer(t)
+	secondary := newListingServer(t, map[int][]int{
+		1: {20, 19},
+		2: {18, 17},
+	})
+	mockDB := &mockTorrentInserter{}
+
+	c, err := NewCrawler(WithDB(mockDB), WithMirrors(primary.URL, secondary.URL))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	results, err := c.ScrapePages(context.Background(), primary.URL+"/?q=foo", 2)
+	if err != nil {
+		t.Fatalf("ScrapePages failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Mirror != secondary.URL {
+			t.Errorf("page %d: expected mirror %s, got %s", r.Page, secondary.URL, r.Mirror)
+		}
+	}
+	if results[1].URL != secondary.URL+"/?p=2&q=foo" {
+		t.Errorf("expected page 2 fetched from the mirror, got %s", results[1].URL)
+	}
+	// The challenged primary is skipped once the mirror has served a page
+	if n := atomic.LoadInt32(primaryHits); n != 1 {
+		t.Errorf("expected 1 request to the challenged primary, got %d", n)
+	}
+	if !mockDB.has(17) || !mockDB.has(20) {
+		t.Errorf("expected torrents from both pages with canonical IDs, got %+v", mockDB.Torrents)
+	}
+}
+
+func TestFailoverOnConnectionError(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+	server := newDetailServer(t, 7)
+
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithMirrors(deadURL, server.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	detail, err := c.ScrapeDetail(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("expected failover to the live mirror, got %v", err)
+	}
+	if detail.Torrent.ID != 7 {
+		t.Errorf("expected torrent 7, got %d", detail.Torrent.ID)
+	}
+}
+
+func TestFailoverPastOpenBreakers(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	tests := []struct {
+		name               string
+		retries, threshold int
+	}{
+		{"default retries and threshold", 3, 5},
+		{"retries above threshold", 5, 2},
+	}
+	for _, tt := range tests {
+		var dead []string
+		for i := 0; i < 2; i++ {
+			server := httptest.NewServer(http.NotFoundHandler())
+			dead = append(dead, server.URL)
+			server.Close()
+		}
+		live := newDetailServer(t, 7)
+
+		c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithMirrors(dead[0], dead[1], live.URL),
+			WithRetryPolicy(policy), WithMaxRetries(tt.retries), WithCircuitBreaker(tt.threshold, time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to create crawler: %v", err)
+		}
+		detail, err := c.ScrapeDetail(context.Background(), 7)
+		if err != nil {
+			t.Fatalf("%s: expected failover to the live mirror, got %v", tt.name, err)
+		}
+		if detail.Torrent.ID != 7 {
+			t.Errorf("%s: expected torrent 7, got %d", tt.name, detail.Torrent.ID)
+		}
+		if s := c.BreakerState(); s != BreakerClosed {
+			t.Errorf("%s: expected the live mirror to keep the crawler's breaker state closed, got %s", tt.name, s)
+		}
+	}
+}
+
+func TestNoFailoverOnNotFound(t *testing.T) {
+	primary := newDetailServer(t)
+	secondary, secondaryHits := newChallengeServer(t)
+
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithMirrors(primary.URL, secondary.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.ScrapeDetail(context.Background(), 7); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from the primary, got %v", err)
+	}
+	if n := atomic.LoadInt32(secondaryHits); n != 0 {
+		t.Errorf("expected no failover for 404, got %d requests to the mirror", n)
+	}
+}
+
+func TestWithMirrorsValidation(t *testing.T) {
+	mockDB := &mockTorrentInserter{}
+	if _, err := NewCrawler(WithDB(mockDB), WithMirrors()); err == nil {
+		t.Error("expected error for an empty mirror list")
+	}
+	if _, err := NewCrawler(WithDB(mockDB), WithMirrors("https://nyaa.si/", "/relative")); err == nil {
+		t.Error("expected error for a relative mirror")
+	}
+
+	c, err := NewCrawler(WithDB(mockDB), WithMirrors("https://nyaa.example/", "https://nyaa.si/"))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if got := c.viewURL(1); got != "https://nyaa.example/view/1" {
+		t.Errorf("expected the first mirror to be the base URL, got %s", got)
+	}
+	if got := c.Mirrors(); len(got) != 2 || got[1] != "https://nyaa.si/" {
+		t.Errorf("unexpected mirror list %v", got)
+	}
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithConcurrency sets how many pages ScrapePages, ScrapeIncremental,
+// Backfill and Reconcile fetch in parallel (default 1). The requests share
+// the rate limiter, circuit breaker, proxies and mirrors, and results are
+// still stored one at a time in page or ID order.
+func WithConcurrency(n int) Option {
+	return func(c *Crawler) error {
+		if n < 1 {
+			return fmt.Errorf("concurrency must be at least 1, got %d", n)
+		}
+		c.concurrency = n
+		return nil
+	}
+}
+
+// outcome is the result of one unit of work in runOrdered
+type outcome[T any] struct {
+	value T
+	err   error
+}
+
+// runOrdered calls work for the indexes 0..n-1 with up to workers calls in
+// flight, and hands each outcome to consume in index order on the calling
+// goroutine, so consume may write to the database without locking. Work
+// starts again only after an earlier outcome is consumed, which keeps at most
+// workers outcomes pending and makes workers=1 strictly sequential.
+//
+// consume returns false to stop early. Work still running is then cancelled,
+// and discard, if not nil, receives every outcome that completed without
+// being consumed so that speculative work can be undone.
+func runOrdered[T any](ctx context.Context, workers, n int,
+	work func(ctx context.Context, i int) (T, error),
+	consume func(i int, value T, err error) (bool, error),
+	discard func(value T, err error),
+) error {
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// pending never holds more than workers entries, so sends never block
+	pending := make(chan chan outcome[T], workers)
+	slots := make(chan struct{}, workers)
+	go func() {
+		defer close(pending)
+		for i := 0; i < n && workCtx.Err() == nil; i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-workCtx.Done():
+				return
+			}
+			done := make(chan outcome[T], 1)
+			pending <- done
+			go func(i int) {
+				value, err := work(workCtx, i)
+				done <- outcome[T]{value: value, err: err}
+			}(i)
+		}
+	}()
+
+	i := 0
+	for done := range pending {
+		o := <-done
+		more, err := consume(i, o.value, o.err)
+		i++
+		<-slots
+		if err != nil || !more {
+			cancel()
+			for done := range pending {
+				o := <-done
+				if discard != nil {
+					discard(o.value, o.err)
+				}
+			}
+			return err
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,239 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyGauge records the highest number of requests served at once
+type concurrencyGauge struct {
+	current int32
+	peak    int32
+}
+
+func (g *concurrencyGauge) enter() {
+	n := atomic.AddInt32(&g.current, 1)
+	for {
+		peak := atomic.LoadInt32(&g.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&g.peak, peak, n) {
+			return
+		}
+	}
+}
+
+func (g *concurrencyGauge) leave() { atomic.AddInt32(&g.current, -1) }
+
+func TestRunOrderedKeepsOrder(t *testing.T) {
+	var gauge concurrencyGauge
+	work := func(ctx context.Context, i int) (int, error) {
+		gauge.enter()
+		defer gauge.leave()
+		// Later indexes finish first
+		time.Sleep(time.Duration(10-i%10) * time.Millisecond)
+		return i * i, nil
+	}
+
+	var got []int
+	consume := func(i int, value int, err error) (bool, error) {
+		if value != i*i {
+			t.Errorf("index %d got value %d", i, value)
+		}
+		got = append(got, i)
+		return true, nil
+	}
+	if err := runOrdered(context.Background(), 3, 20, work, consume, nil); err != nil {
+		t.Fatalf("runOrdered failed: %v", err)
+	}
+
+	if len(got) != 20 {
+		t.Fatalf("expected 20 results, got %d", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("results out of order: %v", got)
+		}
+	}
+	if peak := atomic.LoadInt32(&gauge.peak); peak > 3 || peak < 2 {
+		t.Errorf("expected up to 3 calls in flight, peak was %d", peak)
+	}
+}
+
+func TestRunOrderedStopsAndDiscards(t *testing.T) {
+	var started int32
+	work := func(ctx context.Context, i int) (int, error) {
+		atomic.AddInt32(&started, 1)
+		return i, nil
+	}
+
+	var mu sync.Mutex
+	var discarded []int
+	consume := func(i int, value int, err error) (bool, error) {
+		return i < 2, nil
+	}
+	discard := func(value int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		discarded = append(discarded, value)
+	}
+	if err := runOrdered(context.Background(), 4, 100, work, consume, discard); err != nil {
+		t.Fatalf("runOrdered failed: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&started); n > 3+4 {
+		t.Errorf("expected work to stop shortly after the consumer did, %d calls started", n)
+	}
+	for _, v := range discarded {
+		if v <= 2 {
+			t.Errorf("consumed value %d was also discarded", v)
+		}
+	}
+
+	boom := errors.New("boom")
+	err := runOrdered(context.Background(), 2, 10, work, func(i int, value int, err error) (bool, error) {
+		return false, boom
+	}, nil)
+	if !errors.Is(err, boom) {
+		t.Errorf("expected the consumer's error, got %v", err)
+	}
+}
+
+func TestRunOrderedCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	work := func(ctx context.Context, i int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	var consumed int
+	err := runOrdered(ctx, 2, 1000, work, func(i int, value int, err error) (bool, error) {
+		consumed++
+		return false, err
+	}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if consumed != 1 {
+		t.Errorf("expected the first cancelled result to stop the run, consumed %d", consumed)
+	}
+}
+
+func TestScrapePagesConcurrently(t *testing.T) {
+	var gauge concurrencyGauge
+	pages := map[int][]int{1: {60, 59}, 2: {58, 57}, 3: {56, 55}, 4: {54, 53}, 5: {52, 51}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gauge.enter()
+		defer gauge.leave()
+		page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		if page == 0 {
+			page = 1
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = fmt.Fprint(w, listingHTML(pages[page]...))
+	}))
+	defer server.Close()
+
+	mockDB := &mockTorrentInserter{}
+	c, err := NewCrawler(WithDB(mockDB), WithConcurrency(4))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	results, err := c.ScrapePages(context.Background(), server.URL, 10)
+	if err != nil {
+		t.Fatalf("ScrapePages failed: %v", err)
+	}
+
+	// Pages 1-5 hold torrents, page 6 is empty and ends the crawl
+	if len(results) != 6 {
+		t.Fatalf("expected 6 pages, got %d", len(results))
+	}
+	for i, r := range results {
+		if r.Page != i+1 {
+			t.Errorf("result %d is page %d", i, r.Page)
+		}
+	}
+	for i, torrent := range mockDB.Torrents {
+		if torrent.ID != 60-i {
+			t.Fatalf("torrents stored out of order: %+v", mockDB.Torrents)
+		}
+	}
+	if peak := atomic.LoadInt32(&gauge.peak); peak < 2 {
+		t.Errorf("expected overlapping requests, peak was %d", peak)
+	}
+}
+
+func TestDiscardedPagesAreNotCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"`+r.URL.RawQuery+`"`)
+		if r.URL.Query().Get("p") == "" {
+			_, _ = fmt.Fprint(w, listingHTML(2, 1))
+			return
+		}
+		_, _ = fmt.Fprint(w, listingHTML())
+	}))
+	defer server.Close()
+
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithHTTPCache(""), WithConcurrency(4))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.ScrapePages(context.Background(), server.URL, 6); err != nil {
+		t.Fatalf("ScrapePages failed: %v", err)
+	}
+
+	for page := 1; page <= 6; page++ {
+		target, _ := pageURL(server.URL, page)
+		_, cached := c.cache.get(target)
+		if stored := page <= 2; cached != stored {
+			t.Errorf("page %d: cached=%v, expected %v", page, cached, stored)
+		}
+	}
+}
+
+func TestBackfillConcurrentlySavesProgressInOrder(t *testing.T) {
+	var gauge concurrencyGauge
+	detail := newDetailServer(t, 20, 18, 17, 15, 14, 12)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gauge.enter()
+		defer gauge.leave()
+		time.Sleep(10 * time.Millisecond)
+		detail.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	store := newMockBackfillStore(20)
+	c, err := NewCrawler(WithDB(store), WithBaseURL(server.URL), WithMaxRetries(1), WithConcurrency(3))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	result, err := c.Backfill(context.Background(), BackfillOptions{ToID: 11})
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+
+	if result.Visited != 10 || result.Stored != 6 || result.Missing != 4 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if p := store.progress[defaultBackfillName]; p.NextID != 10 {
+		t.Errorf("expected progress at 10, got %+v", p)
+	}
+	for i, torrent := range store.Torrents {
+		if i > 0 && torrent.ID >= store.Torrents[i-1].ID {
+			t.Fatalf("torrents stored out of ID order: %+v", store.Torrents)
+		}
+	}
+	if peak := atomic.LoadInt32(&gauge.peak); peak < 2 {
+		t.Errorf("expected overlapping requests, peak was %d", peak)
+	}
+
+	if _, err := NewCrawler(WithDB(store), WithConcurrency(0)); err == nil {
+		t.Error("expected error for a concurrency below 1")
+	}
+}
@@ -0,0 +1,242 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyRotation selects when the proxy pool moves to the next proxy
+type ProxyRotation string
+
+const (
+	// RotatePerRequest spreads requests over the pool round-robin
+	RotatePerRequest ProxyRotation = "request"
+	// RotateOnFailure sticks with one proxy until it fails
+	RotateOnFailure ProxyRotation = "failure"
+)
+
+// ParseProxyRotation validates a rotation name from the command line
+func ParseProxyRotation(name string) (ProxyRotation, error) {
+	switch r := ProxyRotation(strings.ToLower(name)); r {
+	case RotatePerRequest, RotateOnFailure:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown proxy rotation: %q (expected request or failure)", name)
+}
+
+// ProxyPoolOptions configures WithProxyPool
+type ProxyPoolOptions struct {
+	// Rotation selects when to move to the next proxy (default RotatePerRequest)
+	Rotation ProxyRotation
+	// EvictFor is how long a proxy that failed is skipped (default 5 minutes)
+	EvictFor time.Duration
+}
+
+// ProxyStat reports how a proxy in the pool has performed
+type ProxyStat struct {
+	Proxy     string
+	Successes int
+	Failures  int
+	Evicted   bool
+}
+
+// SuccessRate returns the fraction of requests through the proxy that got a response
+func (s ProxyStat) SuccessRate() float64 {
+	total := s.Successes + s.Failures
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(total)
+}
+
+// proxyEntry is a proxy in the pool with its own transport
+type proxyEntry struct {
+	url          *url.URL
+	transport    http.RoundTripper
+	successes    int
+	failures     int
+	evictedUntil time.Time
+}
+
+// proxyPool is a RoundTripper that sends each request through one of its
+// proxies. A proxy whose request fails without a response is evicted for a
+// while; if every proxy is evicted the one due back first is used.
+type proxyPool struct {
+	rotation ProxyRotation
+	evictFor time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries []*proxyEntry
+	next    int
+}
+
+func newProxyPool(proxies []string, opts ProxyPoolOptions) (*proxyPool, error) {
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("proxy pool is empty")
+	}
+	if opts.Rotation == "" {
+		opts.Rotation = RotatePerRequest
+	}
+	if opts.Rotation != RotatePerRequest && opts.Rotation != RotateOnFailure {
+		return nil, fmt.Errorf("unknown proxy rotation: %q", opts.Rotation)
+	}
+	if opts.EvictFor <= 0 {
+		opts.EvictFor = 5 * time.Minute
+	}
+
+	pool := &proxyPool{rotation: opts.Rotation, evictFor: opts.EvictFor, now: time.Now}
+	for _, raw := range proxies {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing proxy URL %q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q in %s", u.Scheme, u.Redacted())
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if err := applyProxy(transport, u); err != nil {
+			return nil, err
+		}
+		pool.entries = append(pool.entries, &proxyEntry{url: u, transport: transport})
+	}
+	return pool, nil
+}
+
+// RoundTrip sends the request through the next available proxy
+func (p *proxyPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := p.pick()
+	resp, err := entry.transport.RoundTrip(req)
+	// A cancelled request says nothing about the proxy
+	if err != nil && req.Context().Err() != nil {
+		return resp, err
+	}
+	p.report(entry, err)
+	return resp, err
+}
+
+// pick returns the proxy for the next request
+func (p *proxyPool) pick() *proxyEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	n := len(p.entries)
+	for i := 0; i < n; i++ {
+		idx := (p.next + i) % n
+		if e := p.entries[idx]; !now.Before(e.evictedUntil) {
+			p.next = idx
+			if p.rotation == RotatePerRequest {
+				p.next = (idx + 1) % n
+			}
+			return e
+		}
+	}
+
+	// Every proxy is evicted: use the one due back first rather than failing
+	soonest := p.entries[0]
+	for _, e := range p.entries[1:] {
+		if e.evictedUntil.Before(soonest.evictedUntil) {
+			soonest = e
+		}
+	}
+	return soonest
+}
+
+// report records the outcome of a request through a proxy, evicting it on error
+func (p *proxyPool) report(e *proxyEntry, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		e.successes++
+		e.evictedUntil = time.Time{}
+		return
+	}
+	e.failures++
+	e.evictedUntil = p.now().Add(p.evictFor)
+	stat := e.stat(p.now())
+	log.Printf("Proxy %s failed (%v), evicting for %v (%.0f%% success over %d requests)",
+		stat.Proxy, err, p.evictFor, stat.SuccessRate()*100, stat.Successes+stat.Failures)
+}
+
+func (e *proxyEntry) stat(now time.Time) ProxyStat {
+	return ProxyStat{
+		Proxy:     e.url.Redacted(),
+		Successes: e.successes,
+		Failures:  e.failures,
+		Evicted:   now.Before(e.evictedUntil),
+	}
+}
+
+// stats returns the per-proxy counters in pool order
+func (p *proxyPool) stats() []ProxyStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	stats := make([]ProxyStat, len(p.entries))
+	for i, e := range p.entries {
+		stats[i] = e.stat(now)
+	}
+	return stats
+}
+
+// WithProxyPool sends requests through a pool of http, https, socks5 or
+// socks5h proxies, rotating and evicting them as configured. It replaces WithProxy.
+func WithProxyPool(proxies []string, opts ProxyPoolOptions) Option {
+	return func(c *Crawler) error {
+		pool, err := newProxyPool(proxies, opts)
+		if err != nil {
+			return err
+		}
+		if c.client == nil {
+			c.client = &http.Client{Timeout: 30 * time.Second}
+		}
+		c.client.Transport = pool
+		c.proxies = pool
+		log.Printf("Using a pool of %d proxies, rotating per %s", len(pool.entries), pool.rotation)
+		return nil
+	}
+}
+
+// ProxyStats returns per-proxy success and failure counts, or nil when no
+// proxy pool is configured
+func (c *Crawler) ProxyStats() []ProxyStat {
+	if c.proxies == nil {
+		return nil
+	}
+	return c.proxies.stats()
+}
+
+// LoadProxyList reads proxy URLs from a file, one per line. Blank lines and
+// lines starting with # are skipped.
+func LoadProxyList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var proxies []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		proxies = append(proxies, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading proxy list %s: %w", path, err)
+	}
+	return proxies, nil
+}
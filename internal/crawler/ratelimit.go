@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket per host. Each request takes a token; tokens
+// refill at rps per second up to burst. Requests that find the bucket empty
+// reserve a future token and wait for it, so concurrent callers queue up
+// instead of all retrying at once.
+type rateLimiter struct {
+	rps   float64
+	burst int
+
+	mu    sync.Mutex
+	hosts map[string]*tokenBucket
+}
+
+// tokenBucket holds the token count of a single host. Tokens go negative
+// while requests are waiting for reserved tokens.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	return &rateLimiter{rps: rps, burst: burst, hosts: make(map[string]*tokenBucket)}
+}
+
+// reserve takes a token for host and returns how long to wait before using it
+func (l *rateLimiter) reserve(host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.burst), last: now}
+		l.hosts[host] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rps
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rps * float64(time.Second))
+}
+
+// refund gives back a token reserved for host whose request was never sent,
+// so a cancelled wait does not delay the requests that reserve after it
+func (l *rateLimiter) refund(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		return
+	}
+	b.tokens++
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+}
+
+// jitterRand is seeded once; math/rand's global source is deterministic
+// before Go 1.20
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// randomJitter returns a random duration in [0, max)
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return time.Duration(jitterRand.Int63n(int64(max)))
+}
+
+// WithRateLimit limits requests to rps per second for each host, allowing
+// bursts of up to burst requests. Retries count against the limit too.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Crawler) error {
+		if rps <= 0 {
+			c.limiter = nil
+			return nil
+		}
+		if burst < 1 {
+			return fmt.Errorf("rate limit burst must be at least 1, got %d", burst)
+		}
+		c.limiter = newRateLimiter(rps, burst)
+		return nil
+	}
+}
+
+// WithJitter adds a random pause of up to max before every request
+func WithJitter(max time.Duration) Option {
+	return func(c *Crawler) error {
+		if max < 0 {
+			return fmt.Errorf("jitter must not be negative, got %v", max)
+		}
+		c.jitter = max
+		return nil
+	}
+}
+
+// throttle waits until the rate limit and jitter allow a request to targetURL
+func (c *Crawler) throttle(ctx context.Context, targetURL string) error {
+	var wait time.Duration
+	host := ""
+	if c.limiter != nil {
+		if u, err := url.Parse(targetURL); err == nil {
+			host = u.Host
+		}
+		wait = c.limiter.reserve(host, time.Now())
+	}
+	wait += randomJitter(c.jitter)
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			return nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		if c.limiter != nil {
+			c.limiter.refund(host)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := newRateLimiter(1, 2)
+	start := time.Now()
+
+	if wait := l.reserve("nyaa.si", start); wait != 0 {
+		t.Errorf("first request: expected no wait, got %v", wait)
+	}
+	if wait := l.reserve("nyaa.si", start); wait != 0 {
+		t.Errorf("second request within burst: expected no wait, got %v", wait)
+	}
+	if wait := l.reserve("nyaa.si", start); wait != time.Second {
+		t.Errorf("third request: expected 1s wait, got %v", wait)
+	}
+	if wait := l.reserve("nyaa.si", start); wait != 2*time.Second {
+		t.Errorf("fourth request: expected 2s wait behind the third, got %v", wait)
+	}
+
+	// Other hosts have their own bucket
+	if wait := l.reserve("sukebei.nyaa.si", start); wait != 0 {
+		t.Errorf("other host: expected no wait, got %v", wait)
+	}
+
+	// Once the reserved tokens are paid off the bucket refills up to burst
+	later := start.Add(10 * time.Second)
+	for i := 0; i < 2; i++ {
+		if wait := l.reserve("nyaa.si", later); wait != 0 {
+			t.Errorf("request %d after refill: expected no wait, got %v", i+1, wait)
+		}
+	}
+	if wait := l.reserve("nyaa.si", later); wait == 0 {
+		t.Error("expected refill to be capped at burst")
+	}
+}
+
+func TestRandomJitter(t *testing.T) {
+	if j := randomJitter(0); j != 0 {
+		t.Errorf("expected no jitter for zero max, got %v", j)
+	}
+	for i := 0; i < 100; i++ {
+		if j := randomJitter(50 * time.Millisecond); j < 0 || j >= 50*time.Millisecond {
+			t.Fatalf("jitter %v out of range [0, 50ms)", j)
+		}
+	}
+}
+
+func TestRateLimitOptions(t *testing.T) {
+	mockDB := &mockTorrentInserter{}
+	if _, err := NewCrawler(WithDB(mockDB), WithRateLimit(1, 0)); err == nil {
+		t.Error("expected error for zero burst")
+	}
+	if _, err := NewCrawler(WithDB(mockDB), WithJitter(-time.Second)); err == nil {
+		t.Error("expected error for negative jitter")
+	}
+
+	c, err := NewCrawler(WithDB(mockDB), WithRateLimit(0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error disabling the rate limit: %v", err)
+	}
+	if c.limiter != nil {
+		t.Error("expected zero rps to disable the rate limit")
+	}
+}
+
+func TestThrottleHonorsCancellation(t *testing.T) {
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithRateLimit(0.01, 1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if err := c.throttle(context.Background(), "https://nyaa.si/"); err != nil {
+		t.Fatalf("first request should pass immediately: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.throttle(ctx, "https://nyaa.si/?p=2"); err == nil {
+		t.Error("expected context error while waiting for a token")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("throttle ignored cancellation, waited %v", elapsed)
+	}
+}
+
+func TestRateLimiterRefund(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	start := time.Now()
+
+	l.reserve("nyaa.si", start)
+	if wait := l.reserve("nyaa.si", start); wait != time.Second {
+		t.Fatalf("expected 1s wait, got %v", wait)
+	}
+	// The waiting request is cancelled, so the next one queues behind the
+	// first only
+	l.refund("nyaa.si")
+	if wait := l.reserve("nyaa.si", start); wait != time.Second {
+		t.Errorf("expected the refunded token to be reused with a 1s wait, got %v", wait)
+	}
+
+	// Refunds never raise the bucket above burst
+	later := start.Add(10 * time.Second)
+	l.reserve("nyaa.si", later)
+	l.refund("nyaa.si")
+	l.refund("nyaa.si")
+	l.reserve("nyaa.si", later)
+	if wait := l.reserve("nyaa.si", later); wait == 0 {
+		t.Error("expected refunds to be capped at burst")
+	}
+}
+
+func TestThrottleRefundsCancelledWait(t *testing.T) {
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithRateLimit(0.01, 1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if err := c.throttle(context.Background(), "https://nyaa.si/"); err != nil {
+		t.Fatalf("first request should pass immediately: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		err := c.throttle(ctx, "https://nyaa.si/?p=2")
+		cancel()
+		if err == nil {
+			t.Fatal("expected context error while waiting for a token")
+		}
+	}
+
+	// Without refunds the next request would queue behind the three
+	// cancelled ones, 400s out instead of 100s
+	if wait := c.limiter.reserve("nyaa.si", time.Now()); wait > 101*time.Second {
+		t.Errorf("cancelled waits kept their tokens, next wait is %v", wait)
+	}
+}
+
+func TestScrapePagesRateLimited(t *testing.T) {
+	server := newListingServer(t, map[int][]int{
+		1: {30, 29},
+		2: {28, 27},
+		3: {26, 25},
+	})
+	mockDB := &mockTorrentInserter{}
+
+	c, err := NewCrawler(WithDB(mockDB), WithRateLimit(20, 1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := c.ScrapePages(context.Background(), server.URL, 3); err != nil {
+		t.Fatalf("ScrapePages failed: %v", err)
+	}
+	// The first request uses the burst token, the next two wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected rate-limited crawl to take at least 100ms, took %v", elapsed)
+	}
+	if len(mockDB.Torrents) != 6 {
+		t.Errorf("expected 6 torrents, got %d", len(mockDB.Torrents))
+	}
+}
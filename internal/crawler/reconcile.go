@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// torrentReconciler is implemented by database services that track removed torrents
+type torrentReconciler interface {
+	GetTorrentIDsToReconcile(site string, limit int) ([]int, error)
+	MarkTorrentDeleted(site string, id int) error
+	MarkTorrentChecked(site string, id int) error
+}
+
+// ReconcileOptions configures a reconciliation run
+type ReconcileOptions struct {
+	// Limit is the number of stored torrents to re-check, least recently checked first
+	Limit int
+	// Delay pauses each worker between detail page requests
+	Delay time.Duration
+}
+
+// ReconcileResult summarizes a reconciliation run
+type ReconcileResult struct {
+	Checked int
+	Deleted int
+}
+
+// Reconcile re-checks the /view/{id} pages of stored torrents and marks the
+// ones that now return 404 as deleted. Torrents that are reachable again have
+// their deletion flag cleared.
+func (c *Crawler) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileResult, error) {
+	store, ok := c.dbs.(torrentReconciler)
+	if !ok {
+		return nil, fmt.Errorf("database service does not support reconciliation")
+	}
+	if opts.Limit < 1 {
+		return nil, fmt.Errorf("reconcile limit must be at least 1, got %d", opts.Limit)
+	}
+
+	ids, err := store.GetTorrentIDsToReconcile(c.site.Name, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read torrents to reconcile: %w", err)
+	}
+	log.Printf("Reconciling %d torrents", len(ids))
+
+	result := &ReconcileResult{}
+	check := func(ctx context.Context, i int) (bool, error) {
+		deleted, err := c.isDeleted(ctx, ids[i])
+		if opts.Delay > 0 && i < len(ids)-1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.Delay):
+			}
+		}
+		return deleted, err
+	}
+	record := func(i int, deleted bool, err error) (bool, error) {
+		id := ids[i]
+		if err != nil {
+			return false, fmt.Errorf("torrent %d: %w", id, err)
+		}
+
+		if deleted {
+			err = store.MarkTorrentDeleted(c.site.Name, id)
+			result.Deleted++
+			log.Printf("Torrent %d has been removed", id)
+		} else {
+			err = store.MarkTorrentChecked(c.site.Name, id)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to update torrent %d: %w", id, err)
+		}
+		result.Checked++
+		return true, nil
+	}
+	if err := runOrdered(ctx, c.concurrency, len(ids), check, record, nil); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// isDeleted reports whether the detail page of a torrent is gone (404 or 410)
+func (c *Crawler) isDeleted(ctx context.Context, id int) (bool, error) {
+	_, err := c.fetch(ctx, c.viewURL(id), false)
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockReconciler records reconciliation updates on top of mockTorrentInserter
+type mockReconciler struct {
+	mockTorrentInserter
+	ids     []int
+	deleted map[int]bool
+	checked map[int]bool
+}
+
+func newMockReconciler(ids ...int) *mockReconciler {
+	return &mockReconciler{ids: ids, deleted: make(map[int]bool), checked: make(map[int]bool)}
+}
+
+func (m *mockReconciler) GetTorrentIDsToReconcile(site string, limit int) ([]int, error) {
+	if limit < len(m.ids) {
+		return m.ids[:limit], nil
+	}
+	return m.ids, nil
+}
+
+func (m *mockReconciler) MarkTorrentDeleted(site string, id int) error {
+	m.deleted[id] = true
+	return nil
+}
+
+func (m *mockReconciler) MarkTorrentChecked(site string, id int) error {
+	m.checked[id] = true
+	return nil
+}
+
+func TestReconcile(t *testing.T) {
+	server := newDetailServer(t, 3, 1)
+	store := newMockReconciler(3, 2, 1)
+
+	c, err := NewCrawler(WithDB(store), WithBaseURL(server.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	result, err := c.Reconcile(context.Background(), ReconcileOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	if result.Checked != 3 || result.Deleted != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if !store.deleted[2] || store.deleted[1] || store.deleted[3] {
+		t.Errorf("expected only torrent 2 deleted, got %v", store.deleted)
+	}
+	if !store.checked[1] || !store.checked[3] {
+		t.Errorf("expected torrents 1 and 3 checked, got %v", store.checked)
+	}
+}
+
+func TestReconcileStopsOnServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	store := newMockReconciler(5)
+	c, err := NewCrawler(WithDB(store), WithBaseURL(server.URL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	if _, err := c.Reconcile(context.Background(), ReconcileOptions{Limit: 1}); err == nil {
+		t.Fatal("expected error when the server is unavailable")
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("server errors must not mark torrents deleted, got %v", store.deleted)
+	}
+}
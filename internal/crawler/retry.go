@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. The number of
+// attempts is set separately with WithMaxRetries.
+type RetryPolicy struct {
+	// BaseDelay is the wait after the first failed attempt; it doubles after
+	// every further failure
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff (0 means no cap). When a Retry-After header
+	// asks for a longer wait, the request is not retried.
+	MaxDelay time.Duration
+	// Jitter adds a random extra wait of up to this fraction of the backoff (0 to 1)
+	Jitter float64
+	// RetryableStatus lists the status codes worth retrying. Network errors
+	// are always retried; all other statuses fail immediately.
+	RetryableStatus map[int]bool
+}
+
+// DefaultRetryPolicy retries timeouts, rate limiting and server errors,
+// including Cloudflare's 52x origin errors, with 1s, 2s, 4s... backoff
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		BaseDelay: time.Second,
+		MaxDelay:  time.Minute,
+		Jitter:    0.2,
+		RetryableStatus: map[int]bool{
+			http.StatusRequestTimeout:      true,
+			http.StatusTooManyRequests:     true,
+			http.StatusInternalServerError: true,
+			http.StatusBadGateway:          true,
+			http.StatusServiceUnavailable:  true,
+			http.StatusGatewayTimeout:      true,
+			520:                            true,
+			521:                            true,
+			522:                            true,
+			523:                            true,
+			524:                            true,
+		},
+	}
+}
+
+// WithRetryPolicy sets how failed requests are retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Crawler) error {
+		if policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+			return fmt.Errorf("retry delays must not be negative")
+		}
+		if policy.Jitter < 0 || policy.Jitter > 1 {
+			return fmt.Errorf("retry jitter must be between 0 and 1, got %v", policy.Jitter)
+		}
+		c.retry = policy
+		return nil
+	}
+}
+
+// Retryable reports whether a response with the given status code should be retried
+func (p RetryPolicy) Retryable(statusCode int) bool {
+	return p.RetryableStatus[statusCode]
+}
+
+// Backoff returns the wait after the given failed attempt (starting at 1),
+// without jitter
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			break
+		}
+		// Stop doubling before the duration overflows
+		if d > time.Duration(1<<62) {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// delay returns the wait before retrying after err, honoring Retry-After.
+// It reports false if the server asked for a wait longer than MaxDelay.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	d := p.Backoff(attempt)
+	d += randomJitter(time.Duration(float64(d) * p.Jitter))
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if p.MaxDelay > 0 && statusErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		if statusErr.RetryAfter > d {
+			d = statusErr.RetryAfter
+		}
+	}
+	return d, true
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date. It returns 0 if the header is missing, invalid or in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
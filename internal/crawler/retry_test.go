@@ -0,0 +1,205 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 13, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{" 120 ", 2 * time.Minute},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	uncapped := RetryPolicy{BaseDelay: time.Second}
+	if got := uncapped.Backoff(200); got <= 0 {
+		t.Errorf("Backoff overflowed to %v", got)
+	}
+}
+
+func TestRetryPolicyDelayHonorsRetryAfter(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	d, ok := p.delay(1, &StatusError{StatusCode: 429, RetryAfter: 10 * time.Second})
+	if !ok || d != 10*time.Second {
+		t.Errorf("expected 10s Retry-After wait, got %v (ok=%v)", d, ok)
+	}
+
+	d, ok = p.delay(1, &StatusError{StatusCode: 503, RetryAfter: time.Millisecond})
+	if !ok || d != time.Second {
+		t.Errorf("expected backoff to win over a shorter Retry-After, got %v (ok=%v)", d, ok)
+	}
+
+	if _, ok := p.delay(1, &StatusError{StatusCode: 429, RetryAfter: time.Hour}); ok {
+		t.Error("expected Retry-After beyond MaxDelay to stop retrying")
+	}
+
+	jittered := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5}
+	for i := 0; i < 20; i++ {
+		d, _ := jittered.delay(1, errors.New("boom"))
+		if d < time.Second || d >= 1500*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range [1s, 1.5s)", d)
+		}
+	}
+}
+
+func TestWithRetryPolicyValidation(t *testing.T) {
+	mockDB := &mockTorrentInserter{}
+	if _, err := NewCrawler(WithDB(mockDB), WithRetryPolicy(RetryPolicy{Jitter: 2})); err == nil {
+		t.Error("expected error for jitter above 1")
+	}
+	if _, err := NewCrawler(WithDB(mockDB), WithRetryPolicy(RetryPolicy{BaseDelay: -time.Second})); err == nil {
+		t.Error("expected error for negative delay")
+	}
+	for _, n := range []int{0, -1} {
+		if _, err := NewCrawler(WithDB(mockDB), WithMaxRetries(n)); err == nil {
+			t.Errorf("expected error for %d max retries", n)
+		}
+	}
+}
+
+func TestStatusErrorIs(t *testing.T) {
+	tests := []struct {
+		err         *StatusError
+		notFound    bool
+		rateLimited bool
+	}{
+		{&StatusError{StatusCode: 404}, true, false},
+		{&StatusError{StatusCode: 410}, true, false},
+		{&StatusError{StatusCode: 429}, false, true},
+		{&StatusError{StatusCode: 503, RetryAfter: time.Second}, false, true},
+		{&StatusError{StatusCode: 503}, false, false},
+		{&StatusError{StatusCode: 500}, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, ErrNotFound); got != tt.notFound {
+			t.Errorf("%d: errors.Is(ErrNotFound) = %v, want %v", tt.err.StatusCode, got, tt.notFound)
+		}
+		if got := errors.Is(tt.err, ErrRateLimited); got != tt.rateLimited {
+			t.Errorf("%d: errors.Is(ErrRateLimited) = %v, want %v", tt.err.StatusCode, got, tt.rateLimited)
+		}
+		if errors.Is(tt.err, ErrNetwork) {
+			t.Errorf("%d: status error must not match ErrNetwork", tt.err.StatusCode)
+		}
+	}
+}
+
+// newStatusServer answers with the given status codes in turn, then 200
+func newStatusServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&hits, 1))
+		if n <= len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		_, _ = w.Write([]byte(listingHTML(1)))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func newRetryTestCrawler(t *testing.T) *Crawler {
+	t.Helper()
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 50 * time.Millisecond
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithRetryPolicy(policy), WithMaxRetries(3))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	return c
+}
+
+func TestFetchDoesNotRetryNotFound(t *testing.T) {
+	server, hits := newStatusServer(t, nil, 404, 404, 404)
+	c := newRetryTestCrawler(t)
+
+	_, err := c.fetchWithRetry(context.Background(), server.URL)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("expected a single request for 404, got %d", n)
+	}
+}
+
+func TestFetchRetriesServerErrors(t *testing.T) {
+	server, hits := newStatusServer(t, nil, 503, 502)
+	c := newRetryTestCrawler(t)
+
+	if _, err := c.fetchWithRetry(context.Background(), server.URL); err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if n := atomic.LoadInt32(hits); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestFetchGivesUpOnLongRetryAfter(t *testing.T) {
+	server, hits := newStatusServer(t, http.Header{"Retry-After": {"3600"}}, 429, 429, 429)
+	c := newRetryTestCrawler(t)
+
+	_, err := c.fetchWithRetry(context.Background(), server.URL)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.RetryAfter != time.Hour {
+		t.Errorf("expected Retry-After of 1h on the error, got %v", err)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("expected no retry past MaxDelay, got %d requests", n)
+	}
+}
+
+func TestFetchReportsNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	serverURL := server.URL
+	server.Close()
+	c := newRetryTestCrawler(t)
+
+	_, err := c.fetchWithRetry(context.Background(), serverURL)
+	if !errors.Is(err, ErrNetwork) {
+		t.Fatalf("expected ErrNetwork, got %v", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("network error must not match ErrNotFound")
+	}
+}
@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nyaa-crawler/pkg/magnet"
+	"nyaa-crawler/pkg/models"
+)
+
+// Source selects how listing pages are fetched and parsed
+type Source string
+
+const (
+	// SourceHTML scrapes the HTML listing table
+	SourceHTML Source = "html"
+	// SourceRSS reads the ?page=rss feed
+	SourceRSS Source = "rss"
+)
+
+// ParseSource validates a source name such as "html" or "rss"
+func ParseSource(name string) (Source, error) {
+	switch s := Source(strings.ToLower(name)); s {
+	case SourceHTML, SourceRSS:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown source: %q (expected html or rss)", name)
+	}
+}
+
+// WithSource sets whether listings are scraped from HTML or the RSS feed
+func WithSource(source Source) Option {
+	return func(c *Crawler) error {
+		if _, err := ParseSource(string(source)); err != nil {
+			return err
+		}
+		c.source = source
+		return nil
+	}
+}
+
+// rssFeed is the subset of Nyaa's RSS document the crawler reads.
+// Fields in the nyaa: namespace are matched by local name.
+type rssFeed struct {
+	Items []rssItem `xml:"channel>item"`
+}
+
+type rssItem struct {
+	Title      string `xml:"title"`
+	GUID       string `xml:"guid"`
+	PubDate    string `xml:"pubDate"`
+	Seeders    int    `xml:"seeders"`
+	Leechers   int    `xml:"leechers"`
+	Downloads  int    `xml:"downloads"`
+	InfoHash   string `xml:"infoHash"`
+	CategoryID string `xml:"categoryId"`
+	Category   string `xml:"category"`
+	Size       string `xml:"size"`
+	Trusted    string `xml:"trusted"`
+	Remake     string `xml:"remake"`
+}
+
+// rssURL returns targetURL with Nyaa's page=rss parameter set
+func rssURL(targetURL string) (string, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing URL %q: %w", targetURL, err)
+	}
+
+	q := u.Query()
+	q.Set("page", "rss")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// ParseRSS extracts all torrents from a nyaa.si RSS feed
+func ParseRSS(r io.Reader) ([]models.Torrent, error) {
+	return Nyaa.ParseRSS(r)
+}
+
+// ParseRSS extracts all torrents from the site's RSS feed
+func (s Site) ParseRSS(r io.Reader) ([]models.Torrent, error) {
+	var feed rssFeed
+	if err := xml.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to decode RSS feed: %w", err)
+	}
+
+	var torrents []models.Torrent
+	for _, item := range feed.Items {
+		torrent := s.parseRSSItem(item)
+		if torrent != nil {
+			torrents = append(torrents, *torrent)
+		}
+	}
+
+	return torrents, nil
+}
+
+// parseRSSItem converts a feed item into a torrent, returning nil if it has no valid ID
+func (s Site) parseRSSItem(item rssItem) *models.Torrent {
+	matches := s.IDPattern.FindStringSubmatch(item.GUID)
+	if len(matches) < 2 {
+		return nil
+	}
+	id, err := strconv.Atoi(matches[1])
+	if err != nil || id <= 0 {
+		log.Printf("Warning: failed to parse torrent ID from %q", item.GUID)
+		return nil
+	}
+
+	torrent := &models.Torrent{
+		ID:         id,
+		Name:       strings.TrimSpace(item.Title),
+		Category:   strings.TrimSpace(item.Category),
+		CategoryID: strings.TrimSpace(item.CategoryID),
+		Size:       strings.TrimSpace(item.Size),
+		Seeders:    item.Seeders,
+		Leechers:   item.Leechers,
+		Completed:  item.Downloads,
+		InfoHash:   strings.ToLower(strings.TrimSpace(item.InfoHash)),
+		Trusted:    strings.EqualFold(item.Trusted, "Yes"),
+		Remake:     strings.EqualFold(item.Remake, "Yes"),
+	}
+
+	torrent.SizeBytes = parseSizeBytes(torrent.Size)
+
+	if published, err := time.Parse(time.RFC1123Z, strings.TrimSpace(item.PubDate)); err == nil {
+		torrent.PublishedAt = published.UTC()
+		torrent.Date = torrent.PublishedAt.Format(dateLayout)
+	} else {
+		log.Printf("Warning: failed to parse RSS date %q: %v", item.PubDate, err)
+	}
+
+	if torrent.InfoHash != "" {
+		torrent.Magnet = buildMagnet(torrent.InfoHash, torrent.Name, s.Trackers)
+	}
+
+	return torrent
+}
+
+// buildMagnet creates a magnet link equivalent to the one Nyaa shows in its listing
+func buildMagnet(infoHash, name string, trackers []string) string {
+	m := &magnet.Magnet{InfoHash: infoHash, Name: name, Trackers: trackers}
+	return m.String()
+}
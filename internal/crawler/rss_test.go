@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rssFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:atom="http://www.w3.org/2005/Atom" xmlns:nyaa="https://nyaa.si/xmlns/nyaa" version="2.0">
+	<channel>
+		<title>Nyaa - Home - Torrent File RSS</title>
+		<item>
+			<title>[Group] Show - 01 (1080p).mkv</title>
+			<link>https://nyaa.si/download/1234567.torrent</link>
+			<guid isPermaLink="true">https://nyaa.si/view/1234567</guid>
+			<pubDate>Mon, 01 Jan 2024 12:00:00 -0000</pubDate>
+			<nyaa:seeders>120</nyaa:seeders>
+			<nyaa:leechers>8</nyaa:leechers>
+			<nyaa:downloads>950</nyaa:downloads>
+			<nyaa:infoHash>ABCDEF0123456789ABCDEF0123456789ABCDEF01</nyaa:infoHash>
+			<nyaa:categoryId>1_2</nyaa:categoryId>
+			<nyaa:category>Anime - English-translated</nyaa:category>
+			<nyaa:size>1.4 GiB</nyaa:size>
+			<nyaa:comments>3</nyaa:comments>
+			<nyaa:trusted>Yes</nyaa:trusted>
+			<nyaa:remake>No</nyaa:remake>
+		</item>
+		<item>
+			<title>Broken item</title>
+			<guid isPermaLink="true">https://nyaa.si/user/someone</guid>
+		</item>
+	</channel>
+</rss>`
+
+func TestParseSource(t *testing.T) {
+	for _, name := range []string{"html", "rss", "RSS"} {
+		if _, err := ParseSource(name); err != nil {
+			t.Errorf("ParseSource(%q) returned error: %v", name, err)
+		}
+	}
+	if _, err := ParseSource("atom"); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestParseRSS(t *testing.T) {
+	torrents, err := ParseRSS(strings.NewReader(rssFixture))
+	if err != nil {
+		t.Fatalf("ParseRSS returned error: %v", err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("expected 1 torrent, got %d", len(torrents))
+	}
+
+	got := torrents[0]
+	if got.ID != 1234567 || got.Name != "[Group] Show - 01 (1080p).mkv" {
+		t.Errorf("unexpected ID/name: %d %q", got.ID, got.Name)
+	}
+	if got.Seeders != 120 || got.Leechers != 8 || got.Completed != 950 {
+		t.Errorf("unexpected swarm stats: %d/%d/%d", got.Seeders, got.Leechers, got.Completed)
+	}
+	if got.InfoHash != "abcdef0123456789abcdef0123456789abcdef01" {
+		t.Errorf("unexpected info hash: %q", got.InfoHash)
+	}
+	if got.CategoryID != "1_2" || got.Category != "Anime - English-translated" {
+		t.Errorf("unexpected category: %q %q", got.CategoryID, got.Category)
+	}
+	if got.Size != "1.4 GiB" || got.Date != "2024-01-01 12:00" {
+		t.Errorf("unexpected size/date: %q %q", got.Size, got.Date)
+	}
+	if got.SizeBytes != 1503238554 || !got.PublishedAt.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected parsed size/date: %d %v", got.SizeBytes, got.PublishedAt)
+	}
+	if !got.Trusted || got.Remake {
+		t.Errorf("unexpected trusted/remake: %v/%v", got.Trusted, got.Remake)
+	}
+	if !strings.HasPrefix(got.Magnet, "magnet:?xt=urn:btih:abcdef0123456789abcdef0123456789abcdef01&dn=") {
+		t.Errorf("unexpected magnet: %q", got.Magnet)
+	}
+}
+
+func TestParseRSSInvalid(t *testing.T) {
+	if _, err := ParseRSS(strings.NewReader("<html><body>not a feed")); err == nil {
+		t.Error("expected error for malformed feed")
+	}
+}
+
+func TestScrapePagesRSS(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("page") != "rss" {
+			t.Errorf("expected page=rss query, got %q", r.URL.RawQuery)
+		}
+		_, _ = fmt.Fprint(w, rssFixture)
+	}))
+	defer server.Close()
+
+	mockDB := &mockTorrentInserter{}
+	c, err := NewCrawler(WithDB(mockDB), WithSource(SourceRSS))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	results, err := c.ScrapePages(context.Background(), server.URL+"/?q=show", 5)
+	if err != nil {
+		t.Fatalf("ScrapePages returned error: %v", err)
+	}
+	if requests != 1 || len(results) != 1 {
+		t.Errorf("expected RSS to fetch a single page, got %d requests and %d results", requests, len(results))
+	}
+	if len(mockDB.Torrents) != 1 || mockDB.Torrents[0].ID != 1234567 {
+		t.Errorf("expected torrent 1234567 inserted, got %+v", mockDB.Torrents)
+	}
+}
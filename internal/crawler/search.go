@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// categoryRegex matches Nyaa category codes such as "0_0" or "1_2"
+var categoryRegex = regexp.MustCompile(`^\d+_\d+$`)
+
+// Filter values accepted by Nyaa's "f" search parameter
+const (
+	FilterNone      = 0
+	FilterNoRemakes = 1
+	FilterTrusted   = 2
+)
+
+// validSorts lists the values accepted by Nyaa's "s" search parameter
+var validSorts = map[string]bool{
+	"id":        true,
+	"size":      true,
+	"comments":  true,
+	"seeders":   true,
+	"leechers":  true,
+	"downloads": true,
+}
+
+// SearchQuery holds Nyaa's search parameters. Zero values are omitted from URLs.
+type SearchQuery struct {
+	Query    string // q: search terms
+	Category string // c: category code, e.g. "1_2" for English-translated anime
+	Filter   int    // f: FilterNone, FilterNoRemakes or FilterTrusted
+	Sort     string // s: id, size, comments, seeders, leechers or downloads
+	Order    string // o: asc or desc
+}
+
+// Validate checks every parameter against the values Nyaa accepts
+func (q SearchQuery) Validate() error {
+	if q.Category != "" && !categoryRegex.MatchString(q.Category) {
+		return fmt.Errorf("invalid category %q (expected a code like 1_2)", q.Category)
+	}
+	if q.Filter < FilterNone || q.Filter > FilterTrusted {
+		return fmt.Errorf("invalid filter %d (expected 0, 1 or 2)", q.Filter)
+	}
+	if q.Sort != "" && !validSorts[q.Sort] {
+		return fmt.Errorf("invalid sort %q", q.Sort)
+	}
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return fmt.Errorf("invalid order %q (expected asc or desc)", q.Order)
+	}
+	return nil
+}
+
+// IsNewestFirst reports whether results are ordered by descending ID,
+// which incremental crawls rely on
+func (q SearchQuery) IsNewestFirst() bool {
+	return (q.Sort == "" || q.Sort == "id") && q.Order != "asc"
+}
+
+// BuildURL applies the search parameters to baseURL, preserving any other query values
+func (q SearchQuery) BuildURL(baseURL string) (string, error) {
+	if err := q.Validate(); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing URL %q: %w", baseURL, err)
+	}
+
+	values := u.Query()
+	setParam(values, "q", q.Query)
+	setParam(values, "c", q.Category)
+	if q.Filter != FilterNone {
+		values.Set("f", strconv.Itoa(q.Filter))
+	}
+	setParam(values, "s", q.Sort)
+	setParam(values, "o", q.Order)
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
+
+// setParam sets key to value unless value is empty
+func setParam(values url.Values, key, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
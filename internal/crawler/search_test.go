@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchQueryBuildURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    SearchQuery
+		base     string
+		expected string
+	}{
+		{"empty query", SearchQuery{}, "https://nyaa.si/", "https://nyaa.si/"},
+		{"all parameters",
+			SearchQuery{Query: "[Group] Show", Category: "1_2", Filter: FilterTrusted, Sort: "seeders", Order: "desc"},
+			"https://nyaa.si/",
+			"https://nyaa.si/?c=1_2&f=2&o=desc&q=%5BGroup%5D+Show&s=seeders"},
+		{"keeps existing parameters", SearchQuery{Query: "show"}, "https://nyaa.si/user/group?x=1", "https://nyaa.si/user/group?q=show&x=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.BuildURL(tt.base)
+			if err != nil {
+				t.Fatalf("BuildURL returned error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("BuildURL() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSearchQueryValidate(t *testing.T) {
+	invalid := []SearchQuery{
+		{Category: "anime"},
+		{Filter: 3},
+		{Sort: "name"},
+		{Order: "up"},
+	}
+	for _, q := range invalid {
+		if err := q.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", q)
+		}
+	}
+}
+
+func TestSearchQueryIsNewestFirst(t *testing.T) {
+	tests := []struct {
+		query    SearchQuery
+		expected bool
+	}{
+		{SearchQuery{}, true},
+		{SearchQuery{Sort: "id", Order: "desc"}, true},
+		{SearchQuery{Order: "asc"}, false},
+		{SearchQuery{Sort: "seeders"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.query.IsNewestFirst(); got != tt.expected {
+			t.Errorf("IsNewestFirst(%+v) = %v, want %v", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestSearchWithPagination(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("p") == "" {
+			_, _ = w.Write([]byte(listingHTML(10)))
+			return
+		}
+		_, _ = w.Write([]byte(listingHTML()))
+	}))
+	defer server.Close()
+
+	target, err := SearchQuery{Query: "show", Category: "1_2"}.BuildURL(server.URL + "/")
+	if err != nil {
+		t.Fatalf("BuildURL returned error: %v", err)
+	}
+
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.ScrapePages(context.Background(), target, 3); err != nil {
+		t.Fatalf("ScrapePages returned error: %v", err)
+	}
+
+	expected := []string{"c=1_2&q=show", "c=1_2&p=2&q=show"}
+	if len(queries) != len(expected) {
+		t.Fatalf("expected %d requests, got %d: %v", len(expected), len(queries), queries)
+	}
+	for i := range expected {
+		if queries[i] != expected[i] {
+			t.Errorf("request %d query = %q, want %q", i, queries[i], expected[i])
+		}
+	}
+}
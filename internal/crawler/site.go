@@ -0,0 +1,161 @@
+package crawler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Selectors locate the cells of a listing row. Cell selectors are relative to the row.
+type Selectors struct {
+	Row       string
+	Category  string
+	Title     string
+	Links     string
+	Size      string
+	Date      string
+	Seeders   string
+	Leechers  string
+	Completed string
+}
+
+// Site describes the layout of a Nyaa-compatible tracker
+type Site struct {
+	// Name is stored in the site column to keep IDs from different sites apart
+	Name    string
+	BaseURL string
+	// Categories maps category codes such as "1_2" to their display names
+	Categories map[string]string
+	Selectors  Selectors
+	// Layout is checked by WithLayoutCheck to detect markup changes
+	Layout Layout
+	// IDPattern extracts the torrent ID from a detail page link
+	IDPattern *regexp.Regexp
+	// Trackers are added to magnet links built from RSS info hashes
+	Trackers []string
+}
+
+// nyaaSelectors matches the listing table shared by nyaa.si and sukebei.nyaa.si
+var nyaaSelectors = Selectors{
+	Row:       "tbody tr",
+	Category:  "td:first-child a",
+	Title:     "td:nth-child(2) a",
+	Links:     "td:nth-child(3) a",
+	Size:      "td:nth-child(4)",
+	Date:      "td:nth-child(5)",
+	Seeders:   "td:nth-child(6)",
+	Leechers:  "td:nth-child(7)",
+	Completed: "td:nth-child(8)",
+}
+
+// Nyaa is the profile for nyaa.si
+var Nyaa = Site{
+	Name:    "nyaa",
+	BaseURL: "https://nyaa.si/",
+	Categories: map[string]string{
+		"0_0": "All categories",
+		"1_0": "Anime",
+		"1_1": "Anime - Anime Music Video",
+		"1_2": "Anime - English-translated",
+		"1_3": "Anime - Non-English-translated",
+		"1_4": "Anime - Raw",
+		"2_0": "Audio",
+		"2_1": "Audio - Lossless",
+		"2_2": "Audio - Lossy",
+		"3_0": "Literature",
+		"3_1": "Literature - English-translated",
+		"3_2": "Literature - Non-English-translated",
+		"3_3": "Literature - Raw",
+		"4_0": "Live Action",
+		"4_1": "Live Action - English-translated",
+		"4_2": "Live Action - Idol/Promotional Video",
+		"4_3": "Live Action - Non-English-translated",
+		"4_4": "Live Action - Raw",
+		"5_0": "Pictures",
+		"5_1": "Pictures - Graphics",
+		"5_2": "Pictures - Photos",
+		"6_0": "Software",
+		"6_1": "Software - Applications",
+		"6_2": "Software - Games",
+	},
+	Selectors: nyaaSelectors,
+	Layout:    nyaaLayout,
+	IDPattern: idRegex,
+	Trackers: []string{
+		"http://nyaa.tracker.wf:7777/announce",
+		"udp://open.stealth.si:80/announce",
+		"udp://tracker.opentrackr.org:1337/announce",
+		"udp://exodus.desync.com:6969/announce",
+		"udp://tracker.torrent.eu.org:451/announce",
+	},
+}
+
+// Sukebei is the profile for sukebei.nyaa.si
+var Sukebei = Site{
+	Name:    "sukebei",
+	BaseURL: "https://sukebei.nyaa.si/",
+	Categories: map[string]string{
+		"0_0": "All categories",
+		"1_0": "Art",
+		"1_1": "Art - Anime",
+		"1_2": "Art - Doujinshi",
+		"1_3": "Art - Games",
+		"1_4": "Art - Manga",
+		"1_5": "Art - Pictures",
+		"2_0": "Real Life",
+		"2_1": "Real Life - Photobooks and Pictures",
+		"2_2": "Real Life - Videos",
+	},
+	Selectors: nyaaSelectors,
+	Layout:    nyaaLayout,
+	IDPattern: idRegex,
+	Trackers: []string{
+		"http://sukebei.tracker.wf:8888/announce",
+		"udp://open.stealth.si:80/announce",
+		"udp://tracker.opentrackr.org:1337/announce",
+		"udp://exodus.desync.com:6969/announce",
+		"udp://tracker.torrent.eu.org:451/announce",
+	},
+}
+
+// sites lists the built-in profiles by name
+var sites = map[string]Site{
+	Nyaa.Name:    Nyaa,
+	Sukebei.Name: Sukebei,
+}
+
+// SiteByName returns the built-in profile with the given name
+func SiteByName(name string) (Site, error) {
+	site, ok := sites[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(sites))
+		for n := range sites {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Site{}, fmt.Errorf("unknown site: %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return site, nil
+}
+
+// HasCategory reports whether code is one of the site's category codes
+func (s Site) HasCategory(code string) bool {
+	_, ok := s.Categories[code]
+	return ok
+}
+
+// WithSite sets the site profile used for parsing, URLs and the stored site name.
+// Apply WithBaseURL afterwards to reach the site through a different address.
+func WithSite(site Site) Option {
+	return func(c *Crawler) error {
+		if site.Name == "" {
+			return fmt.Errorf("site profile must have a name")
+		}
+		if site.IDPattern == nil {
+			site.IDPattern = idRegex
+		}
+		c.site = site
+		return WithBaseURL(site.BaseURL)(c)
+	}
+}
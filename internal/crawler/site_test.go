@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSiteByName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"nyaa", "nyaa", false},
+		{"Sukebei", "sukebei", false},
+		{"", "", true},
+		{"example", "", true},
+	}
+
+	for _, tt := range tests {
+		site, err := SiteByName(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("SiteByName(%q): expected error, got %q", tt.name, site.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SiteByName(%q): unexpected error: %v", tt.name, err)
+			continue
+		}
+		if site.Name != tt.want {
+			t.Errorf("SiteByName(%q) = %q, want %q", tt.name, site.Name, tt.want)
+		}
+	}
+}
+
+func TestSiteHasCategory(t *testing.T) {
+	if !Nyaa.HasCategory("1_2") || Nyaa.HasCategory("1_5") {
+		t.Error("expected nyaa to have 1_2 but not 1_5")
+	}
+	if !Sukebei.HasCategory("1_5") || Sukebei.HasCategory("3_1") {
+		t.Error("expected sukebei to have 1_5 but not 3_1")
+	}
+	if Nyaa.Categories["2_2"] == Sukebei.Categories["2_2"] {
+		t.Errorf("expected 2_2 to name different categories, both are %q", Nyaa.Categories["2_2"])
+	}
+}
+
+func TestWithSiteRequiresName(t *testing.T) {
+	if _, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithSite(Site{})); err == nil {
+		t.Error("expected error for site profile without a name")
+	}
+}
+
+func TestScrapePageStampsSite(t *testing.T) {
+	server := newListingServer(t, map[int][]int{1: {12, 11}})
+	mockDB := &mockTorrentInserter{}
+
+	c, err := NewCrawler(WithDB(mockDB), WithSite(Sukebei), WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if err := c.ScrapePage(context.Background(), server.URL); err != nil {
+		t.Fatalf("ScrapePage failed: %v", err)
+	}
+
+	if len(mockDB.Torrents) != 2 {
+		t.Fatalf("expected 2 torrents, got %d", len(mockDB.Torrents))
+	}
+	for _, torrent := range mockDB.Torrents {
+		if torrent.Site != "sukebei" {
+			t.Errorf("torrent %d: expected site sukebei, got %q", torrent.ID, torrent.Site)
+		}
+	}
+}
+
+func TestDefaultSiteIsNyaa(t *testing.T) {
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if c.site.Name != "nyaa" || c.baseURL.String() != Nyaa.BaseURL {
+		t.Errorf("expected nyaa at %s, got %q at %s", Nyaa.BaseURL, c.site.Name, c.baseURL)
+	}
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the lower-cased unit suffixes used by Nyaa to their size in
+// bytes. Nyaa prints binary units; decimal ones are accepted for input typed
+// by hand.
+var sizeUnits = map[string]float64{
+	"":      1,
+	"b":     1,
+	"byte":  1,
+	"bytes": 1,
+	"kib":   1 << 10,
+	"mib":   1 << 20,
+	"gib":   1 << 30,
+	"tib":   1 << 40,
+	"pib":   1 << 50,
+	"kb":    1e3,
+	"mb":    1e6,
+	"gb":    1e9,
+	"tb":    1e12,
+	"pb":    1e15,
+}
+
+// ParseSize converts a size as shown on Nyaa, such as "1.4 GiB" or
+// "743 Bytes", to a number of bytes, rounded to the nearest byte. The space
+// before the unit is optional and a bare number is taken as bytes.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in size %q", s)
+	}
+
+	bytes := math.Round(value * unit)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(bytes), nil
+}
@@ -0,0 +1,37 @@
+package crawler
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"743 Bytes", 743},
+		{"0 Bytes", 0},
+		{"1.0 KiB", 1024},
+		{"120.0 MiB", 125829120},
+		{"1.4 GiB", 1503238554},
+		{"2.5 TiB", 2748779069440},
+		{"500MiB", 524288000},
+		{" 4 gib ", 4294967296},
+		{"1.5 GB", 1500000000},
+		{"2048", 2048},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseSize(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "GiB", "1.4 XiB", "-1 GiB", "1.2.3 MiB", "99999999 PiB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotKind tells how a stored page is parsed
+type SnapshotKind string
+
+const (
+	// SnapshotListing is an HTML listing page
+	SnapshotListing SnapshotKind = "listing"
+	// SnapshotRSS is an RSS listing feed
+	SnapshotRSS SnapshotKind = "rss"
+	// SnapshotDetail is a /view/{id} detail page
+	SnapshotDetail SnapshotKind = "detail"
+)
+
+// snapshotExt is the file extension of stored snapshots
+const snapshotExt = ".gz"
+
+// Snapshot is a fetched page as stored in the snapshot directory. Each
+// snapshot is a gzip file holding the raw body, with the metadata as JSON in
+// the gzip header comment so `zcat` still yields the page.
+type Snapshot struct {
+	URL       string       `json:"url"`
+	Site      string       `json:"site"`
+	Kind      SnapshotKind `json:"kind"`
+	Status    int          `json:"status"`
+	FetchedAt time.Time    `json:"fetched_at"`
+	Body      []byte       `json:"-"`
+}
+
+// WithSnapshotDir stores every listing and detail page the crawler parses in
+// dir, so it can be parsed again later with Reparse
+func WithSnapshotDir(dir string) Option {
+	return func(c *Crawler) error {
+		if dir == "" {
+			return fmt.Errorf("snapshot directory is required")
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("error creating snapshot directory: %w", err)
+		}
+		c.snapshotDir = dir
+		return nil
+	}
+}
+
+// snapshotName returns the file name of a snapshot. Names sort by fetch time.
+func snapshotName(s *Snapshot) string {
+	sum := sha256.Sum256([]byte(s.URL))
+	return fmt.Sprintf("%s-%s-%s%s",
+		s.FetchedAt.UTC().Format("20060102T150405.000000000Z"), s.Kind, hex.EncodeToString(sum[:6]), snapshotExt)
+}
+
+// WriteSnapshot stores s in dir and returns the path of the new file
+func WriteSnapshot(dir string, s *Snapshot) (string, error) {
+	meta, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	zw := gzip.NewWriter(tmp)
+	zw.Comment = string(meta)
+	zw.ModTime = s.FetchedAt
+	if _, err := zw.Write(s.Body); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, snapshotName(s))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ReadSnapshot loads a snapshot written by WriteSnapshot
+func ReadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %w", path, err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	var s Snapshot
+	if err := json.Unmarshal([]byte(zr.Comment), &s); err != nil {
+		return nil, fmt.Errorf("snapshot %s has no metadata: %w", path, err)
+	}
+	if s.Body, err = io.ReadAll(zr); err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// archive stores a fetched page when a snapshot directory is set. Failures
+// are logged and never fail the crawl.
+func (c *Crawler) archive(kind SnapshotKind, page *fetchResult) {
+	if c.snapshotDir == "" {
+		return
+	}
+	s := &Snapshot{
+		URL:       page.URL,
+		Site:      c.site.Name,
+		Kind:      kind,
+		Status:    http.StatusOK,
+		FetchedAt: time.Now(),
+		Body:      page.Body,
+	}
+	if _, err := WriteSnapshot(c.snapshotDir, s); err != nil {
+		log.Printf("Failed to store snapshot of %s: %v", page.URL, err)
+	}
+}
+
+// ReparseResult summarizes a Reparse run
+type ReparseResult struct {
+	Files    int // snapshots parsed
+	Skipped  int // snapshots of another site or with a non-200 status
+	Failed   int // snapshots that could not be read or parsed
+	Found    int
+	Inserted int
+}
+
+// Reparse runs the parsers over every snapshot in dir, oldest first, and
+// stores the torrents that are not stored yet. Stored torrents keep their
+// swarm counts, which are newer than any snapshot. Snapshots of other sites
+// are skipped; unreadable ones are logged and counted as failed.
+func (c *Crawler) Reparse(ctx context.Context, dir string) (*ReparseResult, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), snapshotExt) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots: %w", err)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return filepath.Base(paths[i]) < filepath.Base(paths[j])
+	})
+
+	result := &ReparseResult{}
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		s, err := ReadSnapshot(path)
+		if err != nil {
+			log.Printf("Skipping snapshot: %v", err)
+			result.Failed++
+			continue
+		}
+		if s.Site != c.site.Name || s.Status != http.StatusOK {
+			result.Skipped++
+			continue
+		}
+
+		page, err := c.parseSnapshot(s)
+		if err != nil {
+			log.Printf("Failed to parse snapshot %s (%s): %v", path, s.URL, err)
+			result.Failed++
+			continue
+		}
+		result.Files++
+		result.Found += page.Found
+		result.Inserted += page.Inserted
+	}
+	return result, nil
+}
+
+// parseSnapshot parses the torrents of a single snapshot and stores those
+// that are missing
+func (c *Crawler) parseSnapshot(s *Snapshot) (*PageResult, error) {
+	page, err := c.parsePage(s)
+	if err != nil {
+		return nil, err
+	}
+	return c.storeArchivedRows(page.torrents, page.details)
+}
@@ -0,0 +1,204 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nyaa-crawler/pkg/models"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	fetched := time.Date(2026, 1, 13, 12, 0, 0, 0, time.UTC)
+	want := &Snapshot{
+		URL:       "https://nyaa.si/?p=2",
+		Site:      "nyaa",
+		Kind:      SnapshotListing,
+		Status:    http.StatusOK,
+		FetchedAt: fetched,
+		Body:      []byte(listingHTML(3, 2, 1)),
+	}
+
+	path, err := WriteSnapshot(dir, want)
+	if err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(path), "20260113T120000.000000000Z-listing-") {
+		t.Errorf("unexpected snapshot name %s", filepath.Base(path))
+	}
+
+	got, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatalf("ReadSnapshot failed: %v", err)
+	}
+	if got.URL != want.URL || got.Site != want.Site || got.Kind != want.Kind ||
+		got.Status != want.Status || !got.FetchedAt.Equal(fetched) || string(got.Body) != string(want.Body) {
+		t.Errorf("snapshot changed in the round trip: %+v", got)
+	}
+
+	// The file is plain gzip, so the page can be read with standard tools
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("snapshot is not gzip: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != string(want.Body) {
+		t.Error("expected the gzip payload to be the raw page")
+	}
+}
+
+func TestCrawlStoresSnapshotsAndReparse(t *testing.T) {
+	listing := newListingServer(t, map[int][]int{1: {3, 2, 1}})
+	detail := newDetailServer(t, 7)
+	dir := t.TempDir()
+
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithBaseURL(detail.URL), WithSnapshotDir(dir))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.ScrapePages(context.Background(), listing.URL, 1); err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	if _, err := c.ScrapeDetail(context.Background(), 7); err != nil {
+		t.Fatalf("detail scrape failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d (%v)", len(entries), err)
+	}
+
+	// A snapshot of another site and a corrupt file are not parsed
+	if _, err := WriteSnapshot(dir, &Snapshot{URL: "https://sukebei.nyaa.si/", Site: "sukebei", Kind: SnapshotListing,
+		Status: http.StatusOK, FetchedAt: time.Now(), Body: []byte(listingHTML(9))}); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.gz"), []byte("not gzip"), 0o644); err != nil {
+		t.Fatalf("Failed to write corrupt snapshot: %v", err)
+	}
+
+	mockDB := &mockTorrentInserter{}
+	offline, err := NewCrawler(WithDB(mockDB))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	result, err := offline.Reparse(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Reparse failed: %v", err)
+	}
+	if result.Files != 2 || result.Skipped != 1 || result.Failed != 1 {
+		t.Errorf("expected 2 parsed, 1 skipped and 1 failed snapshot, got %+v", result)
+	}
+	if result.Found != 4 || result.Inserted != 4 {
+		t.Errorf("expected 4 torrents found and inserted, got %+v", result)
+	}
+	for _, id := range []int{1, 2, 3, 7} {
+		if !mockDB.has(id) {
+			t.Errorf("expected torrent %d to be stored by the reparse", id)
+		}
+	}
+}
+
+// mockLiveStore keeps swarm counts like the database does: InsertTorrents
+// refreshes the counts of stored torrents and each refresh adds history
+type mockLiveStore struct {
+	mockTorrentInserter
+	statsRows int
+	details   map[int]models.TorrentDetail
+}
+
+func (m *mockLiveStore) InsertTorrents(torrents []models.Torrent) (int, error) {
+	inserted := 0
+	for _, t := range torrents {
+		if i := m.index(t.ID); i >= 0 {
+			m.Torrents[i].Seeders, m.Torrents[i].Leechers, m.Torrents[i].Completed = t.Seeders, t.Leechers, t.Completed
+			continue
+		}
+		m.Torrents = append(m.Torrents, t)
+		inserted++
+	}
+	return inserted, nil
+}
+
+func (m *mockLiveStore) RecordTorrentStats(torrents []models.Torrent) error {
+	m.statsRows += len(torrents)
+	return nil
+}
+
+func (m *mockLiveStore) InsertMissingTorrents(torrents []models.Torrent) (int, error) {
+	return m.mockTorrentInserter.InsertTorrents(torrents)
+}
+
+func (m *mockLiveStore) SaveMissingTorrentDetail(detail models.TorrentDetail) error {
+	if m.details == nil {
+		m.details = make(map[int]models.TorrentDetail)
+	}
+	if _, ok := m.details[detail.Torrent.ID]; !ok {
+		m.details[detail.Torrent.ID] = detail
+	}
+	return nil
+}
+
+func (m *mockLiveStore) index(id int) int {
+	for i, t := range m.Torrents {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestReparseKeepsLiveStats(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := WriteSnapshot(dir, &Snapshot{URL: "https://nyaa.si/", Site: "nyaa", Kind: SnapshotListing,
+		Status: http.StatusOK, FetchedAt: time.Now().Add(-30 * 24 * time.Hour), Body: []byte(listingHTML(3, 2))}); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+
+	// Torrent 2 was crawled since the snapshot was taken
+	store := &mockLiveStore{}
+	store.Torrents = []models.Torrent{{Site: "nyaa", ID: 2, Name: "Torrent 2", Seeders: 99, Leechers: 9, Completed: 999}}
+	c, err := NewCrawler(WithDB(store))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+
+	result, err := c.Reparse(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Reparse failed: %v", err)
+	}
+	if result.Found != 2 || result.Inserted != 1 || !store.has(3) {
+		t.Errorf("expected only the missing torrent 3 to be inserted, got %+v", result)
+	}
+	if live := store.Torrents[store.index(2)]; live.Seeders != 99 || live.Leechers != 9 || live.Completed != 999 {
+		t.Errorf("expected the newer counts of torrent 2 to be kept, got %+v", live)
+	}
+	if store.statsRows != 0 {
+		t.Errorf("expected no swarm history from a snapshot, got %d rows", store.statsRows)
+	}
+}
+
+func TestWithSnapshotDirValidation(t *testing.T) {
+	if _, err := NewCrawler(WithSnapshotDir("")); err == nil {
+		t.Error("expected error for an empty snapshot directory")
+	}
+	dir := filepath.Join(t.TempDir(), "nested", "snapshots")
+	if _, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithSnapshotDir(dir)); err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("expected the snapshot directory to be created, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialFunc matches http.Transport.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// socksDialer returns a context-aware dial function that connects through a
+// SOCKS5 proxy, authenticating with the user and password in the URL if set.
+// socks5h URLs let the proxy resolve host names (remote DNS); socks5 URLs
+// resolve them locally and send the proxy an IP address.
+func socksDialer(proxyURL *url.URL) (dialFunc, error) {
+	d, err := proxy.FromURL(proxyURL, proxy.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("error creating SOCKS5 proxy dialer: %w", err)
+	}
+	dialer, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer for %s does not support cancellation", proxyURL.Redacted())
+	}
+
+	if proxyURL.Scheme == "socks5h" {
+		return dialer.DialContext, nil
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		resolved, err := resolveLocally(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.DialContext(ctx, network, resolved)
+	}, nil
+}
+
+// resolveLocally replaces the host in addr with one of its IP addresses,
+// preferring IPv4
+func resolveLocally(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no addresses found for %s", host)
+	}
+	ip := ips[0].IP
+	for _, a := range ips {
+		if a.IP.To4() != nil {
+			ip = a.IP
+			break
+		}
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
@@ -0,0 +1,300 @@
+package crawler
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// socksServer is a minimal in-process SOCKS5 server (RFC 1928/1929) that
+// supports CONNECT, optional username/password auth and a fake DNS zone, and
+// records the destination addresses it was asked for
+type socksServer struct {
+	listener net.Listener
+	user     string
+	password string
+	hosts    map[string]string // host name -> address, resolved by the proxy
+	stall    bool              // never answer the greeting
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func newSocksServer(t *testing.T, configure func(s *socksServer)) *socksServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &socksServer{listener: l, hosts: make(map[string]string)}
+	if configure != nil {
+		configure(s)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *socksServer) URL(scheme string) string {
+	u := &url.URL{Scheme: scheme, Host: s.listener.Addr().String()}
+	if s.user != "" {
+		u.User = url.UserPassword(s.user, s.password)
+	}
+	return u.String()
+}
+
+func (s *socksServer) destinations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requested...)
+}
+
+func (s *socksServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *socksServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	if s.stall {
+		_, _ = io.Copy(io.Discard, conn)
+		return
+	}
+
+	// Greeting: VER NMETHODS METHODS...
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	want := byte(0x00)
+	if s.user != "" {
+		want = 0x02
+	}
+	offered := false
+	for _, m := range methods {
+		offered = offered || m == want
+	}
+	if !offered {
+		_, _ = conn.Write([]byte{5, 0xff})
+		return
+	}
+	_, _ = conn.Write([]byte{5, want})
+
+	if want == 0x02 {
+		// VER ULEN UNAME PLEN PASSWD
+		ver := make([]byte, 2)
+		if _, err := io.ReadFull(conn, ver); err != nil {
+			return
+		}
+		user := make([]byte, ver[1])
+		if _, err := io.ReadFull(conn, user); err != nil {
+			return
+		}
+		plen := make([]byte, 1)
+		if _, err := io.ReadFull(conn, plen); err != nil {
+			return
+		}
+		pass := make([]byte, plen[0])
+		if _, err := io.ReadFull(conn, pass); err != nil {
+			return
+		}
+		if string(user) != s.user || string(pass) != s.password {
+			_, _ = conn.Write([]byte{1, 1})
+			return
+		}
+		_, _ = conn.Write([]byte{1, 0})
+	}
+
+	// Request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	case 4:
+		ip := make([]byte, 16)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	default:
+		return
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(conn, portBytes); err != nil {
+		return
+	}
+	dest := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBytes))))
+
+	s.mu.Lock()
+	s.requested = append(s.requested, dest)
+	s.mu.Unlock()
+
+	if addr, ok := s.hosts[host]; ok {
+		dest = addr
+	}
+	upstream, err := net.Dial("tcp", dest)
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer func() { _ = upstream.Close() }()
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go func() { _, _ = io.Copy(upstream, conn) }()
+	_, _ = io.Copy(conn, upstream)
+}
+
+// newSocksCrawler returns a crawler that sends requests through proxyURL
+func newSocksCrawler(t *testing.T, proxyURL string) *Crawler {
+	t.Helper()
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithProxy(proxyURL), WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	return c
+}
+
+func TestSocks5hResolvesRemotely(t *testing.T) {
+	target := newListingServer(t, map[int][]int{1: {5}})
+	targetURL, _ := url.Parse(target.URL)
+	socks := newSocksServer(t, func(s *socksServer) {
+		s.hosts["nyaa.invalid"] = targetURL.Host
+	})
+
+	c := newSocksCrawler(t, socks.URL("socks5h"))
+	if _, err := c.fetchWithRetry(context.Background(), "http://nyaa.invalid:"+targetURL.Port()+"/"); err != nil {
+		t.Fatalf("fetch through socks5h failed: %v", err)
+	}
+	if got := socks.destinations(); len(got) != 1 || got[0] != "nyaa.invalid:"+targetURL.Port() {
+		t.Errorf("expected the proxy to receive the host name, got %v", got)
+	}
+}
+
+func TestSocks5ResolvesLocally(t *testing.T) {
+	target := newListingServer(t, map[int][]int{1: {5}})
+	targetURL, _ := url.Parse(target.URL)
+	socks := newSocksServer(t, func(s *socksServer) {
+		s.hosts["nyaa.invalid"] = targetURL.Host
+	})
+
+	c := newSocksCrawler(t, socks.URL("socks5"))
+	if _, err := c.fetchWithRetry(context.Background(), "http://localhost:"+targetURL.Port()+"/"); err != nil {
+		t.Fatalf("fetch through socks5 failed: %v", err)
+	}
+	got := socks.destinations()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 proxied connection, got %v", got)
+	}
+	if host, _, _ := net.SplitHostPort(got[0]); net.ParseIP(host) == nil {
+		t.Errorf("expected the proxy to receive an IP address, got %s", got[0])
+	}
+
+	// Names only the proxy knows cannot be resolved locally
+	if _, err := c.fetchWithRetry(context.Background(), "http://nyaa.invalid:"+targetURL.Port()+"/"); err == nil {
+		t.Error("expected local resolution of nyaa.invalid to fail")
+	}
+}
+
+func TestSocks5Credentials(t *testing.T) {
+	target := newListingServer(t, map[int][]int{1: {5}})
+	socks := newSocksServer(t, func(s *socksServer) {
+		s.user = "nyaa"
+		s.password = "s3cret"
+	})
+
+	c := newSocksCrawler(t, socks.URL("socks5"))
+	if _, err := c.fetchWithRetry(context.Background(), target.URL); err != nil {
+		t.Fatalf("fetch with valid credentials failed: %v", err)
+	}
+
+	wrong, _ := url.Parse(socks.URL("socks5"))
+	wrong.User = url.UserPassword("nyaa", "wrong")
+	c = newSocksCrawler(t, wrong.String())
+	if _, err := c.fetchWithRetry(context.Background(), target.URL); !errors.Is(err, ErrNetwork) {
+		t.Errorf("expected a network error for bad credentials, got %v", err)
+	}
+
+	c = newSocksCrawler(t, (&url.URL{Scheme: "socks5", Host: wrong.Host}).String())
+	if _, err := c.fetchWithRetry(context.Background(), target.URL); err == nil {
+		t.Error("expected the proxy to refuse a client without credentials")
+	}
+}
+
+func TestSocks5DialHonorsContext(t *testing.T) {
+	socks := newSocksServer(t, func(s *socksServer) { s.stall = true })
+
+	// The dialer itself must give up, not just the HTTP transport waiting on it
+	proxyURL, _ := url.Parse(socks.URL("socks5h"))
+	dial, err := socksDialer(proxyURL)
+	if err != nil {
+		t.Fatalf("Failed to create dialer: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", "nyaa.invalid:80")
+	if err == nil {
+		_ = conn.Close()
+		t.Fatal("expected the stalled proxy handshake to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("dial ignored the context deadline, took %v", elapsed)
+	}
+
+	c := newSocksCrawler(t, socks.URL("socks5h"))
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.fetchWithRetry(ctx, "http://nyaa.invalid/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled from a cancelled fetch, got %v", err)
+	}
+}
+
+func TestProxyPoolSocks5h(t *testing.T) {
+	target := newListingServer(t, map[int][]int{1: {5}})
+	targetURL, _ := url.Parse(target.URL)
+	socks := newSocksServer(t, func(s *socksServer) {
+		s.hosts["nyaa.invalid"] = targetURL.Host
+	})
+
+	c, err := NewCrawler(WithDB(&mockTorrentInserter{}), WithProxyPool([]string{socks.URL("socks5h")}, ProxyPoolOptions{}))
+	if err != nil {
+		t.Fatalf("Failed to create crawler: %v", err)
+	}
+	if _, err := c.fetchWithRetry(context.Background(), "http://nyaa.invalid:"+targetURL.Port()+"/"); err != nil {
+		t.Fatalf("fetch through pooled socks5h proxy failed: %v", err)
+	}
+}
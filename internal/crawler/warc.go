@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// maxWARCRecordSize bounds the block of a WARC record and the decoded body of
+// the HTTP response it stores. Listing and detail pages are far smaller, and
+// the Content-Length of a corrupt archive must not size an allocation.
+const maxWARCRecordSize = 32 << 20
+
+// warcRecord is a single record of a WARC archive
+type warcRecord struct {
+	Type      string
+	TargetURI string
+	Block     []byte
+}
+
+// readWARC calls fn for every record of a WARC 1.0/1.1 archive, one record at
+// a time. Records larger than maxWARCRecordSize are skipped. Compressed
+// archives (.warc.gz, one gzip member per record) must be decompressed by the
+// caller; gzip.Reader reads across members.
+func readWARC(r io.Reader, fn func(warcRecord) error) error {
+	br := bufio.NewReader(r)
+	for {
+		version, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) && strings.TrimSpace(version) == "" {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading WARC record: %w", err)
+		}
+		version = strings.TrimSpace(version)
+		if version == "" {
+			// Blank lines between records
+			continue
+		}
+		if !strings.HasPrefix(version, "WARC/") {
+			return fmt.Errorf("invalid WARC record header %q", version)
+		}
+
+		header, err := textproto.NewReader(br).ReadMIMEHeader()
+		if err != nil {
+			return fmt.Errorf("error reading WARC headers: %w", err)
+		}
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return fmt.Errorf("invalid WARC Content-Length %q", header.Get("Content-Length"))
+		}
+		if length > maxWARCRecordSize {
+			log.Printf("Skipping WARC %s record of %d bytes for %s", header.Get("WARC-Type"), length, header.Get("WARC-Target-URI"))
+			if _, err := io.CopyN(io.Discard, br, length); err != nil {
+				return fmt.Errorf("error skipping WARC record block: %w", err)
+			}
+			continue
+		}
+		block := make([]byte, length)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return fmt.Errorf("error reading WARC record block: %w", err)
+		}
+
+		record := warcRecord{
+			Type:      header.Get("WARC-Type"),
+			TargetURI: strings.Trim(header.Get("WARC-Target-URI"), "<>"),
+			Block:     block,
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// warcResponse parses the HTTP response stored in a WARC response record and
+// returns its status and decoded body
+func warcResponse(record warcRecord) (int, []byte, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(record.Block)), nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid HTTP response for %s: %w", record.TargetURI, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid gzip body for %s: %w", record.TargetURI, err)
+		}
+		defer func() { _ = zr.Close() }()
+		body = zr
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxWARCRecordSize+1))
+	if err != nil {
+		return 0, nil, fmt.Errorf("error reading body of %s: %w", record.TargetURI, err)
+	}
+	if len(data) > maxWARCRecordSize {
+		return 0, nil, fmt.Errorf("body of %s is larger than %d bytes", record.TargetURI, maxWARCRecordSize)
+	}
+	return resp.StatusCode, data, nil
+}
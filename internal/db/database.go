@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"nyaa-crawler/pkg/magnet"
 	"nyaa-crawler/pkg/models"
 
 	"github.com/lib/pq"
@@ -16,6 +18,22 @@ import (
 // Verify DBService implements models.DBService interface
 var _ models.DBService = (*DBService)(nil)
 
+// torrentColumns lists the columns read by scanTorrents, in scan order
+const torrentColumns = "site, id, name, category, size, date, magnet, seeders, leechers, completed, pushed_to_transmission, pushed_to_aria2, deleted_at, COALESCE(size_bytes, 0), published_at, COALESCE(info_hash, '')"
+
+// infoHashIndex is the unique index that deduplicates torrents by info hash
+const infoHashIndex = "idx_torrents_info_hash"
+
+// sortColumns whitelists the fields FindTorrents may order by, mapped to their column
+var sortColumns = map[models.SortField]string{
+	models.SortByID:        "id",
+	models.SortBySeeders:   "seeders",
+	models.SortByLeechers:  "leechers",
+	models.SortByCompleted: "completed",
+	models.SortBySize:      "size_bytes",
+	models.SortByDate:      "published_at",
+}
+
 // DBService handles database operations
 type DBService struct {
 	db *sql.DB
@@ -44,25 +62,127 @@ func NewDBService(connStr string) (*DBService, error) {
 // Migrate creates tables and indexes if they don't exist
 func (dbs *DBService) Migrate() error {
 	sqlStmt := `CREATE TABLE IF NOT EXISTS torrents (
-		id INTEGER PRIMARY KEY,
+		site TEXT NOT NULL DEFAULT 'nyaa',
+		id INTEGER NOT NULL,
 		name TEXT,
 		magnet TEXT,
 		category TEXT,
 		size TEXT,
 		date TEXT,
+		size_bytes BIGINT,
+		published_at TIMESTAMPTZ,
+		info_hash TEXT,
+		seeders INTEGER DEFAULT 0,
+		leechers INTEGER DEFAULT 0,
+		completed INTEGER DEFAULT 0,
 		pushed_to_transmission BOOLEAN DEFAULT FALSE,
-		pushed_to_aria2 BOOLEAN DEFAULT FALSE
+		pushed_to_aria2 BOOLEAN DEFAULT FALSE,
+		checked_at TIMESTAMPTZ,
+		deleted_at TIMESTAMPTZ,
+		PRIMARY KEY (site, id)
 	);`
 	if _, err := dbs.db.Exec(sqlStmt); err != nil {
 		return fmt.Errorf("failed to create torrents table: %w", err)
 	}
 
+	// Add columns introduced after the initial schema to existing tables
+	columns := []string{
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS seeders INTEGER DEFAULT 0;`,
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS leechers INTEGER DEFAULT 0;`,
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS completed INTEGER DEFAULT 0;`,
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS checked_at TIMESTAMPTZ;`,
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS size_bytes BIGINT;`,
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ;`,
+		`ALTER TABLE torrents ADD COLUMN IF NOT EXISTS info_hash TEXT;`,
+	}
+	for _, col := range columns {
+		if _, err := dbs.db.Exec(col); err != nil {
+			return fmt.Errorf("failed to add torrents column: %w", err)
+		}
+	}
+
+	// Create tables keyed by torrent ID
+	tables := []struct {
+		name string
+		stmt string
+	}{
+		{"torrent_stats", `CREATE TABLE IF NOT EXISTS torrent_stats (
+			site TEXT NOT NULL DEFAULT 'nyaa',
+			torrent_id INTEGER NOT NULL,
+			seeders INTEGER NOT NULL,
+			leechers INTEGER NOT NULL,
+			completed INTEGER NOT NULL,
+			recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`},
+		{"torrent_details", `CREATE TABLE IF NOT EXISTS torrent_details (
+			site TEXT NOT NULL DEFAULT 'nyaa',
+			torrent_id INTEGER NOT NULL,
+			description TEXT,
+			submitter TEXT,
+			information_url TEXT,
+			info_hash TEXT,
+			trusted BOOLEAN DEFAULT FALSE,
+			remake BOOLEAN DEFAULT FALSE,
+			comment_count INTEGER DEFAULT 0,
+			file_count INTEGER DEFAULT 0,
+			fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (site, torrent_id)
+		);`},
+		{"torrent_files", `CREATE TABLE IF NOT EXISTS torrent_files (
+			site TEXT NOT NULL DEFAULT 'nyaa',
+			torrent_id INTEGER NOT NULL,
+			path TEXT NOT NULL,
+			size TEXT,
+			PRIMARY KEY (site, torrent_id, path)
+		);`},
+		{"backfill_progress", `CREATE TABLE IF NOT EXISTS backfill_progress (
+			site TEXT NOT NULL DEFAULT 'nyaa',
+			name TEXT NOT NULL,
+			start_id INTEGER NOT NULL,
+			end_id INTEGER NOT NULL,
+			next_id INTEGER NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (site, name)
+		);`},
+		{"missing_torrents", `CREATE TABLE IF NOT EXISTS missing_torrents (
+			site TEXT NOT NULL DEFAULT 'nyaa',
+			id INTEGER NOT NULL,
+			status_code INTEGER NOT NULL,
+			checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (site, id)
+		);`},
+	}
+	for _, table := range tables {
+		if _, err := dbs.db.Exec(table.stmt); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", table.name, err)
+		}
+	}
+
+	if err := dbs.migrateSiteKeys(); err != nil {
+		return err
+	}
+	if err := dbs.backfillParsedColumns(); err != nil {
+		return err
+	}
+	if err := dbs.backfillInfoHashes(); err != nil {
+		return err
+	}
+
 	// Create indexes for better query performance
 	// Note: B-tree index on name is ineffective for LIKE '%pattern%' queries.
 	// For full-text search, consider using pg_trgm GIN index or tsvector.
 	indexes := []string{
 		`CREATE INDEX IF NOT EXISTS idx_torrents_category ON torrents(category);`,
 		`CREATE INDEX IF NOT EXISTS idx_torrents_date ON torrents(date);`,
+		`CREATE INDEX IF NOT EXISTS idx_torrents_seeders ON torrents(seeders);`,
+		`CREATE INDEX IF NOT EXISTS idx_torrents_size_bytes ON torrents(size_bytes);`,
+		`CREATE INDEX IF NOT EXISTS idx_torrents_published_at ON torrents(published_at);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS ` + infoHashIndex + ` ON torrents(info_hash);`,
+		`CREATE INDEX IF NOT EXISTS idx_torrents_checked_at ON torrents(checked_at NULLS FIRST);`,
+		`DROP INDEX IF EXISTS idx_torrent_stats_torrent;`,
+		`CREATE INDEX IF NOT EXISTS idx_torrent_stats_site_torrent ON torrent_stats(site, torrent_id, recorded_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_torrent_stats_recorded ON torrent_stats(recorded_at);`,
 	}
 	for _, idx := range indexes {
 		if _, err := dbs.db.Exec(idx); err != nil {
@@ -73,9 +193,129 @@ func (dbs *DBService) Migrate() error {
 	return nil
 }
 
-// InsertTorrents inserts multiple torrents in a single transaction
-func (dbs *DBService) InsertTorrents(torrents []models.Torrent) error {
-	if len(torrents) == 0 {
+// siteKeys lists the tables that gained a site column, with the primary key
+// each uses once the site is part of it (empty for tables without one)
+var siteKeys = []struct {
+	table string
+	key   string
+}{
+	{"torrents", "site, id"},
+	{"torrent_stats", ""},
+	{"torrent_details", "site, torrent_id"},
+	{"torrent_files", "site, torrent_id, path"},
+	{"backfill_progress", "site, name"},
+	{"missing_torrents", "site, id"},
+}
+
+// migrateSiteKeys upgrades tables created before multi-site support: it adds
+// the site column, assigning existing rows to the default site, and rebuilds
+// primary keys so IDs from different sites don't collide
+func (dbs *DBService) migrateSiteKeys() error {
+	for _, k := range siteKeys {
+		stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS site TEXT NOT NULL DEFAULT '%s';`, k.table, models.DefaultSite)
+		if _, err := dbs.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to add site column to %s: %w", k.table, err)
+		}
+		if k.key == "" {
+			continue
+		}
+
+		stmt = fmt.Sprintf(`DO $$
+		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM pg_index i
+				JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+				WHERE i.indrelid = '%[1]s'::regclass AND i.indisprimary AND a.attname = 'site'
+			) THEN
+				ALTER TABLE %[1]s DROP CONSTRAINT IF EXISTS %[1]s_pkey;
+				ALTER TABLE %[1]s ADD PRIMARY KEY (%[2]s);
+			END IF;
+		END $$;`, k.table, k.key)
+		if _, err := dbs.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate %s primary key: %w", k.table, err)
+		}
+	}
+	return nil
+}
+
+// backfillParsedColumns fills size_bytes and published_at for rows stored
+// before those columns existed, parsing the size and date text the same way
+// the crawler does. Rows whose text does not parse are left NULL.
+func (dbs *DBService) backfillParsedColumns() error {
+	stmts := []struct {
+		column string
+		stmt   string
+	}{
+		{"size_bytes", `UPDATE torrents SET size_bytes = ROUND(split_part(size, ' ', 1)::numeric *
+			CASE split_part(size, ' ', 2)
+				WHEN 'KiB' THEN 1024::numeric
+				WHEN 'MiB' THEN 1024::numeric ^ 2
+				WHEN 'GiB' THEN 1024::numeric ^ 3
+				WHEN 'TiB' THEN 1024::numeric ^ 4
+				WHEN 'PiB' THEN 1024::numeric ^ 5
+				ELSE 1
+			END)
+			WHERE size_bytes IS NULL AND size ~ '^[0-9]+(\.[0-9]+)? (Bytes|B|KiB|MiB|GiB|TiB|PiB)$';`},
+		// Dates are shown in UTC as "2024-01-01 12:00"
+		{"published_at", `UPDATE torrents SET published_at = (date || ':00+00')::timestamptz
+			WHERE published_at IS NULL AND date ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}$';`},
+	}
+	for _, s := range stmts {
+		res, err := dbs.db.Exec(s.stmt)
+		if err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", s.column, err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			log.Printf("Backfilled %s for %d torrents", s.column, n)
+		}
+	}
+	return nil
+}
+
+// backfillInfoHashes fills info_hash from the magnet links of rows stored
+// before the column existed. When several torrents share a hash only the one
+// with the lowest ID, usually the original upload, keeps it, so the unique
+// index can be built.
+func (dbs *DBService) backfillInfoHashes() error {
+	seen := make(map[string]bool)
+	rows, err := dbs.db.Query("SELECT info_hash FROM torrents WHERE info_hash IS NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to read info hashes: %w", err)
+	}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		seen[hash] = true
+	}
+	_ = rows.Close()
+
+	type update struct {
+		site string
+		id   int
+		hash string
+	}
+	var updates []update
+	rows, err = dbs.db.Query("SELECT site, id, magnet FROM torrents WHERE info_hash IS NULL AND magnet LIKE 'magnet:%' ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to read magnet links: %w", err)
+	}
+	for rows.Next() {
+		var u update
+		var uri string
+		if err := rows.Scan(&u.site, &u.id, &uri); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if u.hash = magnet.InfoHash(uri); u.hash != "" && !seen[u.hash] {
+			seen[u.hash] = true
+			updates = append(updates, u)
+		}
+	}
+	_ = rows.Close()
+	if len(updates) == 0 {
 		return nil
 	}
 
@@ -85,31 +325,105 @@ func (dbs *DBService) InsertTorrents(torrents []models.Torrent) error {
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := tx.Prepare("INSERT INTO torrents(id, name, magnet, category, size, date) VALUES($1,$2,$3,$4,$5,$6) ON CONFLICT (id) DO NOTHING")
+	stmt, err := tx.Prepare("UPDATE torrents SET info_hash = $1 WHERE site = $2 AND id = $3")
 	if err != nil {
 		return err
 	}
 	defer func() { _ = stmt.Close() }()
 
+	for _, u := range updates {
+		if _, err := stmt.Exec(u.hash, u.site, u.id); err != nil {
+			return fmt.Errorf("failed to backfill info_hash of torrent %d: %w", u.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Backfilled info_hash for %d torrents", len(updates))
+	return nil
+}
+
+// siteOrDefault returns site, or models.DefaultSite if it is empty
+func siteOrDefault(site string) string {
+	if site == "" {
+		return models.DefaultSite
+	}
+	return site
+}
+
+// InsertTorrents inserts multiple torrents in a single transaction
+// and returns the number of rows that were newly inserted.
+// Torrents that already exist have their swarm statistics refreshed.
+func (dbs *DBService) InsertTorrents(torrents []models.Torrent) (int, error) {
+	if len(torrents) == 0 {
+		return 0, nil
+	}
+
+	tx, err := dbs.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// xmax is 0 only for freshly inserted rows, which distinguishes inserts from updates.
+	// An existing row only takes the info hash if no other row holds it yet: the
+	// migration leaves it NULL on re-uploads of an earlier torrent, and filling it
+	// would violate the unique index and stop their swarm statistics refreshing.
+	stmt, err := tx.Prepare(`INSERT INTO torrents(site, id, name, magnet, category, size, date, seeders, leechers, completed, size_bytes, published_at, info_hash)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,NULLIF($13, ''))
+		ON CONFLICT (site, id) DO UPDATE SET seeders = EXCLUDED.seeders, leechers = EXCLUDED.leechers, completed = EXCLUDED.completed,
+			size_bytes = COALESCE(EXCLUDED.size_bytes, torrents.size_bytes),
+			published_at = COALESCE(EXCLUDED.published_at, torrents.published_at),
+			info_hash = COALESCE(torrents.info_hash, (SELECT EXCLUDED.info_hash
+				WHERE NOT EXISTS (SELECT 1 FROM torrents other WHERE other.info_hash = EXCLUDED.info_hash)))
+		RETURNING (xmax = 0)`)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = stmt.Close() }()
+
 	var insertErrs []error
-	inserted := 0
+	inserted, duplicates := 0, 0
 	for _, t := range torrents {
-		result, err := stmt.Exec(t.ID, t.Name, t.Magnet, t.Category, t.Size, t.Date)
+		// A failed statement aborts the whole transaction, so each row gets a
+		// savepoint to roll back to and the rest of the batch is still stored
+		if _, err := tx.Exec("SAVEPOINT insert_torrent"); err != nil {
+			return 0, err
+		}
+		var isNew bool
+		err := stmt.QueryRow(siteOrDefault(t.Site), t.ID, t.Name, t.Magnet, t.Category, t.Size, t.Date, t.Seeders, t.Leechers, t.Completed,
+			nullSize(t.SizeBytes), nullTime(t.PublishedAt), t.InfoHash).Scan(&isNew)
 		if err != nil {
+			if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT insert_torrent"); rbErr != nil {
+				return 0, rbErr
+			}
 			var pqErr *pq.Error
-			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
-				// unique_violation: expected with ON CONFLICT DO NOTHING, skip
-				continue
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == infoHashIndex {
+				// unique_violation: a new torrent whose info hash is already stored
+				// under another ID, as with re-uploads and torrents posted to both
+				// sites, skip. Existing rows must never fail this way.
+				var exists bool
+				if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM torrents WHERE site = $1 AND id = $2)", siteOrDefault(t.Site), t.ID).Scan(&exists); err != nil {
+					return 0, err
+				}
+				if !exists {
+					duplicates++
+					continue
+				}
 			}
 			insertErrs = append(insertErrs, fmt.Errorf("torrent %d: %w", t.ID, err))
 			continue
 		}
-		affected, _ := result.RowsAffected()
-		inserted += int(affected)
+		if _, err := tx.Exec("RELEASE SAVEPOINT insert_torrent"); err != nil {
+			return 0, err
+		}
+		if isNew {
+			inserted++
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return 0, err
 	}
 
 	if len(insertErrs) > 0 {
@@ -118,41 +432,361 @@ func (dbs *DBService) InsertTorrents(torrents []models.Torrent) error {
 			log.Printf("  Insert error: %v", e)
 		}
 	}
+	if duplicates > 0 {
+		log.Printf("Skipped %d torrents whose info hash is already stored", duplicates)
+	}
 	log.Printf("Batch inserted %d new torrents", inserted)
+	return inserted, nil
+}
+
+// InsertMissingTorrents inserts the torrents that are not stored yet and
+// returns how many were inserted. Stored torrents are left untouched: rows
+// parsed from snapshots and saved pages carry swarm counts from when the page
+// was fetched, which must not replace the live ones. Torrents whose info hash
+// is already stored are skipped too.
+func (dbs *DBService) InsertMissingTorrents(torrents []models.Torrent) (int, error) {
+	if len(torrents) == 0 {
+		return 0, nil
+	}
+
+	tx, err := dbs.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`INSERT INTO torrents(site, id, name, magnet, category, size, date, seeders, leechers, completed, size_bytes, published_at, info_hash)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,NULLIF($13, ''))
+		ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	inserted := 0
+	for _, t := range torrents {
+		res, err := stmt.Exec(siteOrDefault(t.Site), t.ID, t.Name, t.Magnet, t.Category, t.Size, t.Date, t.Seeders, t.Leechers, t.Completed,
+			nullSize(t.SizeBytes), nullTime(t.PublishedAt), t.InfoHash)
+		if err != nil {
+			return 0, fmt.Errorf("torrent %d: %w", t.ID, err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			inserted += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	log.Printf("Inserted %d of %d archived torrents that were not stored yet", inserted, len(torrents))
+	return inserted, nil
+}
+
+// RecordTorrentStats appends a swarm snapshot for each torrent to the history table.
+// All rows in a batch share the transaction timestamp.
+func (dbs *DBService) RecordTorrentStats(torrents []models.Torrent) error {
+	if len(torrents) == 0 {
+		return nil
+	}
+
+	tx, err := dbs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare("INSERT INTO torrent_stats(site, torrent_id, seeders, leechers, completed) VALUES($1,$2,$3,$4,$5)")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, t := range torrents {
+		if _, err := stmt.Exec(siteOrDefault(t.Site), t.ID, t.Seeders, t.Leechers, t.Completed); err != nil {
+			return fmt.Errorf("torrent %d: %w", t.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SaveTorrentDetail stores detail page data, replacing any previously stored file list
+func (dbs *DBService) SaveTorrentDetail(detail models.TorrentDetail) error {
+	t := detail.Torrent
+	site := siteOrDefault(t.Site)
+
+	tx, err := dbs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(`INSERT INTO torrent_details(site, torrent_id, description, submitter, information_url, info_hash, trusted, remake, comment_count, file_count, fetched_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NOW())
+		ON CONFLICT (site, torrent_id) DO UPDATE SET
+			description = EXCLUDED.description, submitter = EXCLUDED.submitter,
+			information_url = EXCLUDED.information_url, info_hash = EXCLUDED.info_hash,
+			trusted = EXCLUDED.trusted, remake = EXCLUDED.remake,
+			comment_count = EXCLUDED.comment_count, file_count = EXCLUDED.file_count,
+			fetched_at = EXCLUDED.fetched_at`,
+		site, t.ID, detail.Description, detail.Submitter, detail.InformationURL, t.InfoHash,
+		t.Trusted, t.Remake, detail.CommentCount, len(detail.Files),
+	)
+	if err != nil {
+		return fmt.Errorf("torrent %d: %w", t.ID, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM torrent_files WHERE site = $1 AND torrent_id = $2", site, t.ID); err != nil {
+		return fmt.Errorf("torrent %d: %w", t.ID, err)
+	}
+	if err := insertTorrentFiles(tx, site, t.ID, detail.Files); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveMissingTorrentDetail stores the detail page data of a torrent whose
+// details are not stored yet, like SaveTorrentDetail. Stored details are left
+// untouched, so an old saved page never replaces a newer one.
+func (dbs *DBService) SaveMissingTorrentDetail(detail models.TorrentDetail) error {
+	t := detail.Torrent
+	site := siteOrDefault(t.Site)
+
+	tx, err := dbs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`INSERT INTO torrent_details(site, torrent_id, description, submitter, information_url, info_hash, trusted, remake, comment_count, file_count, fetched_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NOW())
+		ON CONFLICT (site, torrent_id) DO NOTHING`,
+		site, t.ID, detail.Description, detail.Submitter, detail.InformationURL, t.InfoHash,
+		t.Trusted, t.Remake, detail.CommentCount, len(detail.Files),
+	)
+	if err != nil {
+		return fmt.Errorf("torrent %d: %w", t.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if err := insertTorrentFiles(tx, site, t.ID, detail.Files); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertTorrentFiles stores the file list of a torrent within tx
+func insertTorrentFiles(tx *sql.Tx, site string, id int, files []models.TorrentFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare("INSERT INTO torrent_files(site, torrent_id, path, size) VALUES($1,$2,$3,$4) ON CONFLICT DO NOTHING")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, f := range files {
+		if _, err := stmt.Exec(site, id, f.Path, f.Size); err != nil {
+			return fmt.Errorf("torrent %d file %q: %w", id, f.Path, err)
+		}
+	}
 	return nil
 }
 
-// Close closes the database connection
-func (dbs *DBService) Close() {
-	_ = dbs.db.Close()
+// GetTorrentDetail retrieves a torrent together with its stored detail page data.
+// It returns sql.ErrNoRows if the detail page has not been scraped.
+func (dbs *DBService) GetTorrentDetail(site string, id int) (*models.TorrentDetail, error) {
+	detail := &models.TorrentDetail{}
+	t := &detail.Torrent
+	site = siteOrDefault(site)
+
+	err := dbs.db.QueryRow("SELECT "+torrentColumns+" FROM torrents WHERE site = $1 AND id = $2", site, id).Scan(torrentScanDest(t)...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbs.db.QueryRow(
+		"SELECT description, submitter, information_url, info_hash, trusted, remake, comment_count FROM torrent_details WHERE site = $1 AND torrent_id = $2",
+		site, id,
+	).Scan(&detail.Description, &detail.Submitter, &detail.InformationURL, &t.InfoHash, &t.Trusted, &t.Remake, &detail.CommentCount)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := dbs.db.Query("SELECT path, size FROM torrent_files WHERE site = $1 AND torrent_id = $2 ORDER BY path", site, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var f models.TorrentFile
+		if err := rows.Scan(&f.Path, &f.Size); err != nil {
+			return nil, err
+		}
+		detail.Files = append(detail.Files, f)
+	}
+
+	return detail, nil
 }
 
-// GetAllTorrents retrieves torrents from the database with a safety limit
-func (dbs *DBService) GetAllTorrents() ([]models.Torrent, error) {
-	rows, err := dbs.db.Query("SELECT id, name, magnet, category, size, date FROM torrents LIMIT 10000")
+// GetTorrentIDsToReconcile returns stored torrent IDs that were never checked
+// or checked longest ago, so repeated runs cycle through the whole table
+func (dbs *DBService) GetTorrentIDsToReconcile(site string, limit int) ([]int, error) {
+	rows, err := dbs.db.Query(
+		"SELECT id FROM torrents WHERE site = $1 ORDER BY checked_at ASC NULLS FIRST, id DESC LIMIT $2",
+		siteOrDefault(site), limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
-	var torrents []models.Torrent
+	var ids []int
 	for rows.Next() {
-		var t models.Torrent
-		err := rows.Scan(&t.ID, &t.Name, &t.Magnet, &t.Category, &t.Size, &t.Date)
-		if err != nil {
+		var id int
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		torrents = append(torrents, t)
+		ids = append(ids, id)
 	}
+	return ids, nil
+}
 
-	return torrents, nil
+// MarkTorrentDeleted flags a torrent as removed, keeping the original deletion time
+func (dbs *DBService) MarkTorrentDeleted(site string, id int) error {
+	_, err := dbs.db.Exec(
+		"UPDATE torrents SET deleted_at = COALESCE(deleted_at, NOW()), checked_at = NOW() WHERE site = $1 AND id = $2",
+		siteOrDefault(site), id,
+	)
+	return err
+}
+
+// MarkTorrentChecked records that a torrent is still available, clearing any deletion flag
+func (dbs *DBService) MarkTorrentChecked(site string, id int) error {
+	_, err := dbs.db.Exec("UPDATE torrents SET deleted_at = NULL, checked_at = NOW() WHERE site = $1 AND id = $2", siteOrDefault(site), id)
+	return err
+}
+
+// GetBackfillProgress retrieves the progress of a backfill job, or nil if it has never run
+func (dbs *DBService) GetBackfillProgress(site, name string) (*models.BackfillProgress, error) {
+	p := &models.BackfillProgress{}
+	err := dbs.db.QueryRow(
+		"SELECT site, name, start_id, end_id, next_id, updated_at FROM backfill_progress WHERE site = $1 AND name = $2",
+		siteOrDefault(site), name,
+	).Scan(&p.Site, &p.Name, &p.StartID, &p.EndID, &p.NextID, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SaveBackfillProgress creates or updates the progress of a backfill job
+func (dbs *DBService) SaveBackfillProgress(progress models.BackfillProgress) error {
+	_, err := dbs.db.Exec(`INSERT INTO backfill_progress(site, name, start_id, end_id, next_id, updated_at)
+		VALUES($1,$2,$3,$4,$5,NOW())
+		ON CONFLICT (site, name) DO UPDATE SET start_id = EXCLUDED.start_id, end_id = EXCLUDED.end_id,
+			next_id = EXCLUDED.next_id, updated_at = EXCLUDED.updated_at`,
+		siteOrDefault(progress.Site), progress.Name, progress.StartID, progress.EndID, progress.NextID,
+	)
+	return err
+}
+
+// MarkTorrentMissing records an ID whose detail page returned an error status such as 404
+func (dbs *DBService) MarkTorrentMissing(site string, id int, statusCode int) error {
+	_, err := dbs.db.Exec(`INSERT INTO missing_torrents(site, id, status_code, checked_at) VALUES($1,$2,$3,NOW())
+		ON CONFLICT (site, id) DO UPDATE SET status_code = EXCLUDED.status_code, checked_at = EXCLUDED.checked_at`,
+		siteOrDefault(site), id, statusCode,
+	)
+	return err
+}
+
+// GetTorrentStats retrieves the most recent swarm snapshots for a torrent, newest first
+func (dbs *DBService) GetTorrentStats(site string, id int, limit int) ([]models.TorrentStats, error) {
+	rows, err := dbs.db.Query(
+		"SELECT site, torrent_id, seeders, leechers, completed, recorded_at FROM torrent_stats WHERE site = $1 AND torrent_id = $2 ORDER BY recorded_at DESC LIMIT $3",
+		siteOrDefault(site), id, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stats []models.TorrentStats
+	for rows.Next() {
+		var s models.TorrentStats
+		if err := rows.Scan(&s.Site, &s.TorrentID, &s.Seeders, &s.Leechers, &s.Completed, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// GetFastestGrowing ranks torrents by completed downloads gained within the window,
+// breaking ties by seeder growth. Torrents need at least two snapshots in the window.
+func (dbs *DBService) GetFastestGrowing(window time.Duration, limit int) ([]models.TorrentGrowth, error) {
+	rows, err := dbs.db.Query(`SELECT `+torrentColumns+`, g.seeders_delta, g.completed_delta
+		FROM (
+			SELECT site AS stats_site, torrent_id,
+				(array_agg(seeders ORDER BY recorded_at DESC))[1] - (array_agg(seeders ORDER BY recorded_at ASC))[1] AS seeders_delta,
+				MAX(completed) - MIN(completed) AS completed_delta
+			FROM torrent_stats
+			WHERE recorded_at >= NOW() - ($1 * INTERVAL '1 second')
+			GROUP BY site, torrent_id
+			HAVING COUNT(*) > 1
+		) g
+		JOIN torrents ON torrents.site = g.stats_site AND torrents.id = g.torrent_id
+		WHERE torrents.deleted_at IS NULL
+		ORDER BY g.completed_delta DESC, g.seeders_delta DESC
+		LIMIT $2`,
+		window.Seconds(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var growth []models.TorrentGrowth
+	for rows.Next() {
+		var g models.TorrentGrowth
+		dest := append(torrentScanDest(&g.Torrent), &g.SeedersDelta, &g.CompletedDelta)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		growth = append(growth, g)
+	}
+	return growth, nil
+}
+
+// Close closes the database connection
+func (dbs *DBService) Close() {
+	_ = dbs.db.Close()
+}
+
+// GetAllTorrents retrieves torrents from the database with a safety limit
+func (dbs *DBService) GetAllTorrents() ([]models.Torrent, error) {
+	rows, err := dbs.db.Query("SELECT " + torrentColumns + " FROM torrents LIMIT 10000")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanTorrents(rows)
 }
 
 // GetTorrentsByPattern retrieves torrents matching a pattern
 func (dbs *DBService) GetTorrentsByPattern(pattern string, limit int) ([]models.Torrent, error) {
 	likePattern := "%" + pattern + "%"
 	rows, err := dbs.db.Query(
-		"SELECT id, name, category, size, date, magnet, pushed_to_transmission, pushed_to_aria2 FROM torrents WHERE name LIKE $1 ORDER BY id DESC LIMIT $2",
+		"SELECT "+torrentColumns+" FROM torrents WHERE name LIKE $1 AND deleted_at IS NULL ORDER BY id DESC LIMIT $2",
 		likePattern, limit,
 	)
 	if err != nil {
@@ -166,7 +800,7 @@ func (dbs *DBService) GetTorrentsByPattern(pattern string, limit int) ([]models.
 // GetLatestTorrents retrieves the latest torrents
 func (dbs *DBService) GetLatestTorrents(limit int) ([]models.Torrent, error) {
 	rows, err := dbs.db.Query(
-		"SELECT id, name, category, size, date, magnet, pushed_to_transmission, pushed_to_aria2 FROM torrents ORDER BY id DESC LIMIT $1",
+		"SELECT "+torrentColumns+" FROM torrents WHERE deleted_at IS NULL ORDER BY id DESC LIMIT $1",
 		limit,
 	)
 	if err != nil {
@@ -177,34 +811,121 @@ func (dbs *DBService) GetLatestTorrents(limit int) ([]models.Torrent, error) {
 	return scanTorrents(rows)
 }
 
-// GetTorrentCount returns the total count and magnet count
+// FindTorrents retrieves torrents matching a filter, ordered by the whitelisted sort column
+func (dbs *DBService) FindTorrents(filter models.TorrentFilter) ([]models.Torrent, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = models.SortByID
+	}
+	sortColumn, ok := sortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort field: %s", sortBy)
+	}
+
+	var conditions []string
+	var args []interface{}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if filter.Site != "" {
+		args = append(args, filter.Site)
+		conditions = append(conditions, fmt.Sprintf("site = $%d", len(args)))
+	}
+	if filter.Pattern != "" {
+		args = append(args, "%"+filter.Pattern+"%")
+		conditions = append(conditions, fmt.Sprintf("name LIKE $%d", len(args)))
+	}
+	if filter.MinSeeders > 0 {
+		args = append(args, filter.MinSeeders)
+		conditions = append(conditions, fmt.Sprintf("seeders >= $%d", len(args)))
+	}
+	if filter.MinSize > 0 {
+		args = append(args, filter.MinSize)
+		conditions = append(conditions, fmt.Sprintf("size_bytes >= $%d", len(args)))
+	}
+	if filter.MaxSize > 0 {
+		args = append(args, filter.MaxSize)
+		conditions = append(conditions, fmt.Sprintf("size_bytes <= $%d", len(args)))
+	}
+	if !filter.PublishedAfter.IsZero() {
+		args = append(args, filter.PublishedAfter)
+		conditions = append(conditions, fmt.Sprintf("published_at >= $%d", len(args)))
+	}
+	if !filter.PublishedBefore.IsZero() {
+		args = append(args, filter.PublishedBefore)
+		conditions = append(conditions, fmt.Sprintf("published_at < $%d", len(args)))
+	}
+
+	query := "SELECT " + torrentColumns + " FROM torrents"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	// Torrents whose size or date could not be parsed sort last
+	query += fmt.Sprintf(" ORDER BY %s DESC NULLS LAST, id DESC LIMIT $%d", sortColumn, len(args))
+
+	rows, err := dbs.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanTorrents(rows)
+}
+
+// GetTorrentByInfoHash retrieves the torrent with a normalized (lower-case hex)
+// info hash, or nil if none is stored
+func (dbs *DBService) GetTorrentByInfoHash(infoHash string) (*models.Torrent, error) {
+	t := &models.Torrent{}
+	err := dbs.db.QueryRow("SELECT "+torrentColumns+" FROM torrents WHERE info_hash = $1", infoHash).Scan(torrentScanDest(t)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetTorrentCount returns the total count and magnet count of torrents not deleted
 func (dbs *DBService) GetTorrentCount() (total, withMagnet int, err error) {
-	err = dbs.db.QueryRow("SELECT COUNT(*), COUNT(CASE WHEN magnet != '' THEN 1 END) FROM torrents").Scan(&total, &withMagnet)
+	err = dbs.db.QueryRow("SELECT COUNT(*), COUNT(CASE WHEN magnet != '' THEN 1 END) FROM torrents WHERE deleted_at IS NULL").Scan(&total, &withMagnet)
 	return
 }
 
+// GetMaxTorrentID returns the highest torrent ID stored for a site, or 0 if it has none
+func (dbs *DBService) GetMaxTorrentID(site string) (int, error) {
+	var maxID int
+	err := dbs.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM torrents WHERE site = $1", siteOrDefault(site)).Scan(&maxID)
+	return maxID, err
+}
+
 // GetMatchCount returns the count of torrents matching a pattern
 func (dbs *DBService) GetMatchCount(pattern string) (int, error) {
 	likePattern := "%" + pattern + "%"
 	var count int
-	err := dbs.db.QueryRow("SELECT COUNT(*) FROM torrents WHERE name LIKE $1", likePattern).Scan(&count)
+	err := dbs.db.QueryRow("SELECT COUNT(*) FROM torrents WHERE name LIKE $1 AND deleted_at IS NULL", likePattern).Scan(&count)
 	return count, err
 }
 
 // UpdatePushedStatus updates the pushed status for a torrent
-func (dbs *DBService) UpdatePushedStatus(id int, target models.PushTarget) error {
+func (dbs *DBService) UpdatePushedStatus(site string, id int, target models.PushTarget) error {
 	column := string(target)
 	if target != models.PushTargetTransmission && target != models.PushTargetAria2 {
 		return fmt.Errorf("invalid push target: %s", column)
 	}
-	_, err := dbs.db.Exec("UPDATE torrents SET "+column+" = TRUE WHERE id = $1", id)
+	_, err := dbs.db.Exec("UPDATE torrents SET "+column+" = TRUE WHERE site = $1 AND id = $2", siteOrDefault(site), id)
 	return err
 }
 
 // DeleteAll removes all torrents from the database (for testing only)
 func (dbs *DBService) DeleteAll() error {
-	_, err := dbs.db.Exec("DELETE FROM torrents")
-	return err
+	for _, table := range []string{"torrent_stats", "torrent_details", "torrent_files", "backfill_progress", "missing_torrents", "torrents"} {
+		if _, err := dbs.db.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // scanTorrents reads all torrent records from the rows
@@ -212,11 +933,47 @@ func scanTorrents(rows *sql.Rows) ([]models.Torrent, error) {
 	var torrents []models.Torrent
 	for rows.Next() {
 		var t models.Torrent
-		err := rows.Scan(&t.ID, &t.Name, &t.Category, &t.Size, &t.Date, &t.Magnet, &t.PushedToTransmission, &t.PushedToAria2)
-		if err != nil {
+		if err := rows.Scan(torrentScanDest(&t)...); err != nil {
 			return nil, err
 		}
 		torrents = append(torrents, t)
 	}
 	return torrents, nil
 }
+
+// torrentScanDest returns scan destinations for torrentColumns, in order
+func torrentScanDest(t *models.Torrent) []interface{} {
+	return []interface{}{&t.Site, &t.ID, &t.Name, &t.Category, &t.Size, &t.Date, &t.Magnet,
+		&t.Seeders, &t.Leechers, &t.Completed, &t.PushedToTransmission, &t.PushedToAria2, &t.DeletedAt,
+		&t.SizeBytes, timeScanner{&t.PublishedAt}, &t.InfoHash}
+}
+
+// timeScanner scans a nullable timestamp, leaving the zero time for NULL
+type timeScanner struct {
+	t *time.Time
+}
+
+func (s timeScanner) Scan(src interface{}) error {
+	var nt sql.NullTime
+	if err := nt.Scan(src); err != nil {
+		return err
+	}
+	*s.t = nt.Time
+	return nil
+}
+
+// nullSize returns a parsed size for storage, or NULL if it is unknown
+func nullSize(n int64) interface{} {
+	if n <= 0 {
+		return nil
+	}
+	return n
+}
+
+// nullTime returns a timestamp for storage, or NULL if it is unknown
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
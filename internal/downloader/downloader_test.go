@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"nyaa-crawler/pkg/magnet"
 )
 
 func TestParseTransmissionURL(t *testing.T) {
@@ -151,3 +153,44 @@ func TestAria2ClientError(t *testing.T) {
 		t.Error("expected error for aria2 error response, got nil")
 	}
 }
+
+// recordingDownloader records the magnet links it receives
+type recordingDownloader struct {
+	magnets []string
+}
+
+func (r *recordingDownloader) AddMagnet(magnet string) error {
+	r.magnets = append(r.magnets, magnet)
+	return nil
+}
+
+func TestTrackerRewriter(t *testing.T) {
+	next := &recordingDownloader{}
+	dl := NewTrackerRewriter(next, magnet.Rewriter{
+		Add:    []string{"udp://tracker.example.org:1337/announce", "http://nyaa.tracker.wf:7777/announce"},
+		Strip:  []string{"dead.example.com"},
+		Dedupe: true,
+	})
+
+	hash := "abcdef0123456789abcdef0123456789abcdef01"
+	if err := dl.AddMagnet("magnet:?xt=urn:btih:" + hash + "&dn=Show&tr=http%3A%2F%2Fnyaa.tracker.wf%3A7777%2Fannounce&tr=udp%3A%2F%2Fdead.example.com%3A80"); err != nil {
+		t.Fatalf("AddMagnet failed: %v", err)
+	}
+	// Links that cannot be parsed are still sent
+	if err := dl.AddMagnet("magnet:?xt=urn:btih:test"); err != nil {
+		t.Fatalf("AddMagnet failed: %v", err)
+	}
+
+	want := []string{
+		"magnet:?xt=urn:btih:" + hash + "&dn=Show&tr=http%3A%2F%2Fnyaa.tracker.wf%3A7777%2Fannounce&tr=udp%3A%2F%2Ftracker.example.org%3A1337%2Fannounce",
+		"magnet:?xt=urn:btih:test",
+	}
+	if len(next.magnets) != len(want) {
+		t.Fatalf("expected %d magnets, got %v", len(want), next.magnets)
+	}
+	for i := range want {
+		if next.magnets[i] != want[i] {
+			t.Errorf("magnet %d = %q, want %q", i, next.magnets[i], want[i])
+		}
+	}
+}
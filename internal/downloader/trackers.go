@@ -0,0 +1,30 @@
+package downloader
+
+import (
+	"log"
+
+	"nyaa-crawler/pkg/magnet"
+)
+
+// TrackerRewriter is a Downloader that rewrites the trackers of each magnet
+// link before passing it on to another Downloader
+type TrackerRewriter struct {
+	next     Downloader
+	rewriter magnet.Rewriter
+}
+
+// NewTrackerRewriter wraps next so every magnet link it receives is rewritten first
+func NewTrackerRewriter(next Downloader, rewriter magnet.Rewriter) *TrackerRewriter {
+	return &TrackerRewriter{next: next, rewriter: rewriter}
+}
+
+// AddMagnet rewrites the magnet link's trackers and sends it to the wrapped
+// Downloader. A link that cannot be parsed is sent unchanged.
+func (t *TrackerRewriter) AddMagnet(uri string) error {
+	rewritten, err := t.rewriter.Rewrite(uri)
+	if err != nil {
+		log.Printf("Warning: sending magnet link without rewriting trackers: %v", err)
+		rewritten = uri
+	}
+	return t.next.AddMagnet(rewritten)
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronNext(t *testing.T) {
+	// Tuesday
+	base := time.Date(2026, 1, 13, 12, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 13, 12, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 13, 12, 15, 0, 0, time.UTC)},
+		{"5 * * * *", time.Date(2026, 1, 13, 13, 5, 0, 0, time.UTC)},
+		{"0,30 9-17 * * *", time.Date(2026, 1, 13, 12, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 1, 14, 3, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"10/20 * * * *", time.Date(2026, 1, 13, 12, 10, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches (the 15th or a Friday)
+		{"0 0 15 * 5", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 1, 13, 13, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) failed: %v", tt.spec, err)
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronNeverMatches(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron failed: %v", err)
+	}
+	if got := schedule.Next(time.Date(2026, 1, 13, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("expected February 30 never to match, got %v", got)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@fortnightly",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	base := time.Date(2026, 1, 13, 12, 7, 30, 0, time.UTC)
+
+	schedule, err := Parse("@every 90s")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := schedule.Next(base); !got.Equal(base.Add(90 * time.Second)) {
+		t.Errorf("expected a 90s interval, got %v", got)
+	}
+
+	if _, err := Parse("*/5 * * * *"); err != nil {
+		t.Errorf("expected a cron expression to parse, got %v", err)
+	}
+	if _, err := Parse("@every soon"); err == nil {
+		t.Error("expected error for an invalid interval")
+	}
+	if _, err := Every(0); err == nil {
+		t.Error("expected error for a zero interval")
+	}
+}
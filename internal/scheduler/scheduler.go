@@ -0,0 +1,145 @@
+// Package scheduler runs a job repeatedly on an interval or cron schedule,
+// never running two instances of the job at once.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is one scheduled run. Its context is cancelled when the scheduler shuts down.
+type Job func(ctx context.Context) error
+
+// Summary describes a finished run
+type Summary struct {
+	Run      int
+	Started  time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// Scheduler runs a Job on a Schedule. A run that comes due while the previous
+// one is still in progress is skipped rather than queued.
+type Scheduler struct {
+	schedule  Schedule
+	job       Job
+	immediate bool
+	onFinish  func(Summary)
+	now       func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	runs    int
+	skipped int
+}
+
+// Option configures a Scheduler
+type Option func(*Scheduler)
+
+// RunImmediately starts the first run when Run is called instead of waiting
+// for the schedule
+func RunImmediately() Option {
+	return func(s *Scheduler) {
+		s.immediate = true
+	}
+}
+
+// OnFinish registers a callback that receives the summary of every run, in
+// addition to the summary the scheduler logs
+func OnFinish(fn func(Summary)) Option {
+	return func(s *Scheduler) {
+		s.onFinish = fn
+	}
+}
+
+// New creates a scheduler that runs job on schedule
+func New(schedule Schedule, job Job, opts ...Option) *Scheduler {
+	s := &Scheduler{schedule: schedule, job: job, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run runs the job on the schedule until ctx is cancelled or the schedule
+// ends. On shutdown it cancels the run in progress through its context and
+// waits for it to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	next := s.schedule.Next(s.now())
+	if s.immediate {
+		next = s.now()
+	}
+	for {
+		if next.IsZero() {
+			log.Println("Schedule has no further runs")
+			return
+		}
+		log.Printf("Next run at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Println("Scheduler stopping, waiting for the current run to finish")
+			return
+		case <-timer.C:
+		}
+
+		if run, ok := s.start(); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.execute(ctx, run)
+			}()
+		} else {
+			log.Printf("Skipping run due at %s: the previous run is still in progress", next.Format(time.RFC3339))
+		}
+		next = s.schedule.Next(s.now())
+	}
+}
+
+// start claims the next run, or reports false when a run is in progress
+func (s *Scheduler) start() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.skipped++
+		return 0, false
+	}
+	s.running = true
+	s.runs++
+	return s.runs, true
+}
+
+// execute runs the job once and logs its summary
+func (s *Scheduler) execute(ctx context.Context, run int) {
+	summary := Summary{Run: run, Started: s.now()}
+	log.Printf("Run %d started", run)
+	summary.Err = s.job(ctx)
+	summary.Duration = s.now().Sub(summary.Started)
+
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+
+	if summary.Err != nil {
+		log.Printf("Run %d failed after %v: %v", run, summary.Duration.Round(time.Millisecond), summary.Err)
+	} else {
+		log.Printf("Run %d finished in %v", run, summary.Duration.Round(time.Millisecond))
+	}
+	if s.onFinish != nil {
+		s.onFinish(summary)
+	}
+}
+
+// Stats returns the number of runs started and skipped so far
+func (s *Scheduler) Stats() (runs, skipped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs, s.skipped
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runFor runs s until d has passed and returns how long Run took to return
+func runFor(s *Scheduler, d time.Duration) time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	start := time.Now()
+	s.Run(ctx)
+	return time.Since(start)
+}
+
+func TestSchedulerRunsOnInterval(t *testing.T) {
+	schedule, _ := Every(20 * time.Millisecond)
+	var runs int32
+	s := New(schedule, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, RunImmediately())
+
+	runFor(s, 110*time.Millisecond)
+
+	// Runs at 0, 20, 40, 60, 80 and 100ms
+	if n := atomic.LoadInt32(&runs); n < 4 || n > 6 {
+		t.Errorf("expected about 6 runs, got %d", n)
+	}
+	if started, skipped := s.Stats(); int32(started) != atomic.LoadInt32(&runs) || skipped != 0 {
+		t.Errorf("unexpected stats: %d started, %d skipped", started, skipped)
+	}
+}
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	schedule, _ := Every(10 * time.Millisecond)
+	var active, peak int32
+	s := New(schedule, func(ctx context.Context) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		if n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		time.Sleep(35 * time.Millisecond)
+		return nil
+	}, RunImmediately())
+
+	runFor(s, 100*time.Millisecond)
+
+	if p := atomic.LoadInt32(&peak); p != 1 {
+		t.Errorf("expected runs never to overlap, peak was %d", p)
+	}
+	if _, skipped := s.Stats(); skipped == 0 {
+		t.Error("expected runs that came due during a long run to be skipped")
+	}
+}
+
+func TestSchedulerShutdownWaitsForRun(t *testing.T) {
+	schedule, _ := Every(time.Hour)
+	var cancelled int32
+	s := New(schedule, func(ctx context.Context) error {
+		<-ctx.Done()
+		// A graceful job finishes its current step before returning
+		time.Sleep(30 * time.Millisecond)
+		atomic.StoreInt32(&cancelled, 1)
+		return ctx.Err()
+	}, RunImmediately())
+
+	elapsed := runFor(s, 20*time.Millisecond)
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Error("expected Run to wait for the cancelled run to return")
+	}
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("Run returned after %v, before the run finished", elapsed)
+	}
+}
+
+func TestSchedulerSummaries(t *testing.T) {
+	schedule, _ := Every(15 * time.Millisecond)
+	boom := errors.New("boom")
+	var calls int32
+
+	var mu sync.Mutex
+	var summaries []Summary
+	s := New(schedule, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 2 {
+			return boom
+		}
+		return nil
+	}, OnFinish(func(summary Summary) {
+		mu.Lock()
+		defer mu.Unlock()
+		summaries = append(summaries, summary)
+	}))
+
+	runFor(s, 55*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(summaries) < 2 {
+		t.Fatalf("expected at least 2 summaries, got %d", len(summaries))
+	}
+	if summaries[0].Run != 1 || summaries[0].Err != nil {
+		t.Errorf("unexpected first summary %+v", summaries[0])
+	}
+	if summaries[1].Run != 2 || !errors.Is(summaries[1].Err, boom) {
+		t.Errorf("expected the second run to report its error, got %+v", summaries[1])
+	}
+}
+
+// endingSchedule fires once and then ends
+type endingSchedule struct{ fired bool }
+
+func (e *endingSchedule) Next(after time.Time) time.Time {
+	if e.fired {
+		return time.Time{}
+	}
+	e.fired = true
+	return after.Add(time.Millisecond)
+}
+
+func TestSchedulerStopsWhenScheduleEnds(t *testing.T) {
+	var runs int32
+	s := New(&endingSchedule{}, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+
+	if elapsed := runFor(s, time.Second); elapsed > 500*time.Millisecond {
+		t.Errorf("expected Run to return when the schedule ended, took %v", elapsed)
+	}
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected 1 run, got %d", n)
+	}
+}
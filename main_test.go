@@ -50,7 +50,7 @@ func TestInsertAndGetTorrent(t *testing.T) {
 		Date:     "2026-01-13",
 	}
 
-	if err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
+	if _, err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
 		t.Fatalf("Failed to insert torrent: %v", err)
 	}
 
@@ -81,12 +81,16 @@ func TestInsertDuplicateTorrent(t *testing.T) {
 		Date:     "2026-01-13",
 	}
 
-	if err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
+	if _, err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
 		t.Fatalf("Failed to insert torrent first time: %v", err)
 	}
-	if err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
+	inserted, err := dbs.InsertTorrents([]models.Torrent{torrent})
+	if err != nil {
 		t.Fatalf("Failed to insert torrent second time: %v", err)
 	}
+	if inserted != 0 {
+		t.Errorf("Expected 0 newly inserted torrents on duplicate, got %d", inserted)
+	}
 
 	torrents, err := dbs.GetAllTorrents()
 	if err != nil {
@@ -108,7 +112,7 @@ func TestInsertTorrentsBatch(t *testing.T) {
 		{ID: 300, Name: "Batch 3", Magnet: "magnet:3", Category: "C", Size: "3GB", Date: "2026-01-13"},
 	}
 
-	if err := dbs.InsertTorrents(torrents); err != nil {
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
 		t.Fatalf("Failed to batch insert: %v", err)
 	}
 
@@ -125,7 +129,7 @@ func TestInsertTorrentsBatch(t *testing.T) {
 func TestInsertEmptyBatch(t *testing.T) {
 	dbs := setupTestDB(t)
 
-	if err := dbs.InsertTorrents([]models.Torrent{}); err != nil {
+	if _, err := dbs.InsertTorrents([]models.Torrent{}); err != nil {
 		t.Errorf("Expected no error on empty batch, got: %v", err)
 	}
 }
@@ -143,17 +147,17 @@ func TestUpdatePushedStatus(t *testing.T) {
 		Date:     "2026-01-13",
 	}
 
-	if err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
+	if _, err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
 		t.Fatalf("Failed to insert torrent: %v", err)
 	}
 
 	// Test valid target
-	if err := dbs.UpdatePushedStatus(500, models.PushTargetTransmission); err != nil {
+	if err := dbs.UpdatePushedStatus(models.DefaultSite, 500, models.PushTargetTransmission); err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
 
 	// Test invalid target (should return error)
-	if err := dbs.UpdatePushedStatus(500, models.PushTarget("invalid_column")); err == nil {
+	if err := dbs.UpdatePushedStatus(models.DefaultSite, 500, models.PushTarget("invalid_column")); err == nil {
 		t.Error("Expected error for invalid target, got nil")
 	}
 }
@@ -168,7 +172,7 @@ func TestGetTorrentsByPattern(t *testing.T) {
 		{ID: 1003, Name: "One Piece Episode 2", Magnet: "magnet:3", Category: "Anime", Size: "1GB", Date: "2026-01-13"},
 	}
 
-	if err := dbs.InsertTorrents(torrents); err != nil {
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
 		t.Fatalf("Failed to insert torrents: %v", err)
 	}
 
@@ -192,7 +196,7 @@ func TestGetLatestTorrents(t *testing.T) {
 		{ID: 2003, Name: "Torrent C", Magnet: "magnet:c", Category: "Anime", Size: "1GB", Date: "2026-01-15"},
 	}
 
-	if err := dbs.InsertTorrents(torrents); err != nil {
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
 		t.Fatalf("Failed to insert torrents: %v", err)
 	}
 
@@ -219,7 +223,7 @@ func TestGetTorrentCount(t *testing.T) {
 		{ID: 3002, Name: "Without Magnet", Magnet: "", Category: "Test", Size: "1GB", Date: "2026-01-13"},
 	}
 
-	if err := dbs.InsertTorrents(torrents); err != nil {
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
 		t.Fatalf("Failed to insert torrents: %v", err)
 	}
 
@@ -246,7 +250,7 @@ func TestGetMatchCount(t *testing.T) {
 		{ID: 4003, Name: "Test Match B", Magnet: "magnet:c", Category: "Test", Size: "1GB", Date: "2026-01-13"},
 	}
 
-	if err := dbs.InsertTorrents(torrents); err != nil {
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
 		t.Fatalf("Failed to insert torrents: %v", err)
 	}
 
@@ -260,6 +264,475 @@ func TestGetMatchCount(t *testing.T) {
 	}
 }
 
+func TestGetMaxTorrentID(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	maxID, err := dbs.GetMaxTorrentID(models.DefaultSite)
+	if err != nil {
+		t.Fatalf("Failed to get max ID: %v", err)
+	}
+	if maxID != 0 {
+		t.Errorf("Expected max ID 0 on empty table, got %d", maxID)
+	}
+
+	torrents := []models.Torrent{
+		{ID: 5001, Name: "Low", Magnet: "magnet:a", Category: "Test", Size: "1GB", Date: "2026-01-13"},
+		{ID: 5003, Name: "High", Magnet: "magnet:b", Category: "Test", Size: "1GB", Date: "2026-01-13"},
+	}
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+
+	maxID, err = dbs.GetMaxTorrentID(models.DefaultSite)
+	if err != nil {
+		t.Fatalf("Failed to get max ID: %v", err)
+	}
+	if maxID != 5003 {
+		t.Errorf("Expected max ID 5003, got %d", maxID)
+	}
+}
+
+func TestSitesKeepSeparateIDs(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	torrents := []models.Torrent{
+		{Site: "nyaa", ID: 5101, Name: "Nyaa", Magnet: "magnet:n", Category: "Test", Size: "1GB", Date: "2026-01-13"},
+		{Site: "sukebei", ID: 5101, Name: "Sukebei", Magnet: "magnet:s", Category: "Test", Size: "1GB", Date: "2026-01-13"},
+		{Site: "sukebei", ID: 5200, Name: "Sukebei High", Magnet: "magnet:h", Category: "Test", Size: "1GB", Date: "2026-01-13"},
+	}
+	inserted, err := dbs.InsertTorrents(torrents)
+	if err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+	if inserted != 3 {
+		t.Errorf("Expected 3 new torrents across sites, got %d", inserted)
+	}
+
+	maxID, err := dbs.GetMaxTorrentID("nyaa")
+	if err != nil {
+		t.Fatalf("Failed to get max ID: %v", err)
+	}
+	if maxID != 5101 {
+		t.Errorf("Expected nyaa max ID 5101, got %d", maxID)
+	}
+
+	results, err := dbs.FindTorrents(models.TorrentFilter{Site: "sukebei", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to find torrents: %v", err)
+	}
+	if len(results) != 2 || results[0].Site != "sukebei" {
+		t.Errorf("Expected 2 sukebei torrents, got %+v", results)
+	}
+}
+
+func TestInsertTorrentsRefreshesSwarmStats(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	torrent := models.Torrent{ID: 6001, Name: "Swarm", Magnet: "magnet:s", Category: "Test", Size: "1GB", Date: "2026-01-13", Seeders: 5}
+	if _, err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
+		t.Fatalf("Failed to insert torrent: %v", err)
+	}
+
+	torrent.Seeders = 50
+	torrent.Completed = 7
+	inserted, err := dbs.InsertTorrents([]models.Torrent{torrent})
+	if err != nil {
+		t.Fatalf("Failed to re-insert torrent: %v", err)
+	}
+	if inserted != 0 {
+		t.Errorf("Expected refresh to insert 0 new torrents, got %d", inserted)
+	}
+
+	results, err := dbs.GetLatestTorrents(1)
+	if err != nil {
+		t.Fatalf("Failed to get torrents: %v", err)
+	}
+	if len(results) != 1 || results[0].Seeders != 50 || results[0].Completed != 7 {
+		t.Errorf("Expected refreshed seeders 50 and completed 7, got %+v", results)
+	}
+}
+
+func TestInsertMissingTorrentsKeepsLiveStats(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	live := models.Torrent{ID: 6101, Name: "Live", Magnet: "magnet:l", Category: "Test", Size: "1GB", Date: "2026-01-13", Seeders: 50, Completed: 70}
+	if _, err := dbs.InsertTorrents([]models.Torrent{live}); err != nil {
+		t.Fatalf("Failed to insert torrent: %v", err)
+	}
+
+	// An old saved page still lists the torrent with the counts of its day
+	stale := live
+	stale.Seeders, stale.Completed = 5, 7
+	archived := models.Torrent{ID: 6102, Name: "Archived", Magnet: "magnet:a", Category: "Test", Size: "1GB", Date: "2026-01-12", Seeders: 3}
+	inserted, err := dbs.InsertMissingTorrents([]models.Torrent{stale, archived})
+	if err != nil {
+		t.Fatalf("Failed to insert missing torrents: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("Expected only the missing torrent to be inserted, got %d", inserted)
+	}
+
+	detail := models.TorrentDetail{Torrent: live, Submitter: "uploader", CommentCount: 9}
+	if err := dbs.SaveTorrentDetail(detail); err != nil {
+		t.Fatalf("Failed to save detail: %v", err)
+	}
+	detail.Torrent = stale
+	detail.CommentCount = 1
+	if err := dbs.SaveMissingTorrentDetail(detail); err != nil {
+		t.Fatalf("Failed to save missing detail: %v", err)
+	}
+
+	got, err := dbs.GetTorrentDetail(models.DefaultSite, 6101)
+	if err != nil {
+		t.Fatalf("Failed to get detail: %v", err)
+	}
+	if got.Torrent.Seeders != 50 || got.Torrent.Completed != 70 {
+		t.Errorf("Expected live seeders 50 and completed 70 to be kept, got %d/%d", got.Torrent.Seeders, got.Torrent.Completed)
+	}
+	if got.CommentCount != 9 {
+		t.Errorf("Expected the stored detail to be kept, got %d comments", got.CommentCount)
+	}
+}
+
+func TestFindTorrents(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	torrents := []models.Torrent{
+		{ID: 7001, Name: "Show A", Magnet: "magnet:a", Category: "Anime", Size: "1GB", Date: "2026-01-13", Seeders: 3},
+		{ID: 7002, Name: "Show B", Magnet: "magnet:b", Category: "Anime", Size: "1GB", Date: "2026-01-13", Seeders: 30},
+		{ID: 7003, Name: "Other", Magnet: "magnet:c", Category: "Anime", Size: "1GB", Date: "2026-01-13", Seeders: 20},
+	}
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+
+	results, err := dbs.FindTorrents(models.TorrentFilter{Pattern: "Show", MinSeeders: 2, SortBy: models.SortBySeeders, Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to find torrents: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != 7002 {
+		t.Errorf("Expected 7002 first of 2 results, got %+v", results)
+	}
+
+	if _, err := dbs.FindTorrents(models.TorrentFilter{SortBy: "name; DROP TABLE torrents", Limit: 10}); err == nil {
+		t.Error("Expected error for invalid sort field, got nil")
+	}
+}
+
+func TestFindTorrentsBySizeAndDate(t *testing.T) {
+	dbs := setupTestDB(t)
+	if err := dbs.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	_ = dbs.DeleteAll()
+
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 12, 0, 0, 0, time.UTC) }
+	torrents := []models.Torrent{
+		{ID: 7101, Name: "Small", Size: "900.0 MiB", SizeBytes: 943718400, Date: "2026-01-10 12:00", PublishedAt: day(10)},
+		{ID: 7102, Name: "Medium", Size: "1.4 GiB", SizeBytes: 1503238554, Date: "2026-01-12 12:00", PublishedAt: day(12)},
+		{ID: 7103, Name: "Large", Size: "12.0 GiB", SizeBytes: 12884901888, Date: "2026-01-11 12:00", PublishedAt: day(11)},
+		{ID: 7104, Name: "Unknown", Size: "?", Date: "?"},
+	}
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+
+	bySize, err := dbs.FindTorrents(models.TorrentFilter{SortBy: models.SortBySize, Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to find torrents: %v", err)
+	}
+	if len(bySize) != 4 || bySize[0].ID != 7103 || bySize[1].ID != 7102 || bySize[3].ID != 7104 {
+		t.Errorf("Expected largest first and unknown sizes last, got %+v", bySize)
+	}
+	if !bySize[0].PublishedAt.Equal(day(11)) || bySize[0].SizeBytes != 12884901888 {
+		t.Errorf("Expected parsed columns to round-trip, got %+v", bySize[0])
+	}
+	if !bySize[3].PublishedAt.IsZero() || bySize[3].SizeBytes != 0 {
+		t.Errorf("Expected unknown size and date to read back as zero, got %+v", bySize[3])
+	}
+
+	ranged, err := dbs.FindTorrents(models.TorrentFilter{
+		MinSize:         1 << 30,
+		PublishedBefore: day(12),
+		SortBy:          models.SortByDate,
+		Limit:           10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to find torrents: %v", err)
+	}
+	if len(ranged) != 1 || ranged[0].ID != 7103 {
+		t.Errorf("Expected only 7103 in range, got %+v", ranged)
+	}
+}
+
+func TestInfoHashDeduplication(t *testing.T) {
+	dbs := setupTestDB(t)
+	if err := dbs.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	_ = dbs.DeleteAll()
+
+	hash := "abcdef0123456789abcdef0123456789abcdef01"
+	torrents := []models.Torrent{
+		{ID: 7201, Name: "Original", Magnet: "magnet:?xt=urn:btih:" + hash, InfoHash: hash},
+		// A re-upload of the same files on the other site
+		{Site: "sukebei", ID: 9201, Name: "Re-upload", Magnet: "magnet:?xt=urn:btih:" + hash, InfoHash: hash},
+		{ID: 7202, Name: "Unrelated", Magnet: "magnet:?xt=urn:btih:0123", InfoHash: ""},
+	}
+	inserted, err := dbs.InsertTorrents(torrents)
+	if err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+	// The duplicate is skipped without aborting the rest of the batch
+	if inserted != 2 {
+		t.Errorf("Expected 2 torrents inserted, got %d", inserted)
+	}
+
+	found, err := dbs.GetTorrentByInfoHash(hash)
+	if err != nil {
+		t.Fatalf("Failed to look up info hash: %v", err)
+	}
+	if found == nil || found.ID != 7201 || found.InfoHash != hash {
+		t.Errorf("Expected torrent 7201, got %+v", found)
+	}
+
+	missing, err := dbs.GetTorrentByInfoHash("0000000000000000000000000000000000000000")
+	if err != nil || missing != nil {
+		t.Errorf("Expected no torrent for an unknown hash, got %+v, %v", missing, err)
+	}
+}
+
+func TestDuplicateInfoHashRowKeepsRefreshing(t *testing.T) {
+	dbs := setupTestDB(t)
+	if err := dbs.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	_ = dbs.DeleteAll()
+
+	// A re-upload stored without its info hash, as the migration leaves rows
+	// whose hash repeats an earlier ID
+	hash := "abcdef0123456789abcdef0123456789abcdef02"
+	stored := []models.Torrent{
+		{ID: 7301, Name: "Original", Magnet: "magnet:?xt=urn:btih:" + hash, InfoHash: hash, Seeders: 5},
+		{ID: 7302, Name: "Re-upload", Magnet: "magnet:?xt=urn:btih:" + hash, Seeders: 1},
+	}
+	if _, err := dbs.InsertTorrents(stored); err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+
+	// Re-crawling the re-upload now carries its parsed info hash
+	recrawled := models.Torrent{ID: 7302, Name: "Re-upload", Magnet: "magnet:?xt=urn:btih:" + hash, InfoHash: hash, Seeders: 40, Leechers: 3, Completed: 90}
+	inserted, err := dbs.InsertTorrents([]models.Torrent{recrawled})
+	if err != nil {
+		t.Fatalf("Failed to re-insert torrent: %v", err)
+	}
+	if inserted != 0 {
+		t.Errorf("Expected no new torrents, got %d", inserted)
+	}
+
+	results, err := dbs.FindTorrents(models.TorrentFilter{Pattern: "Re-upload", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to find torrents: %v", err)
+	}
+	if len(results) != 1 || results[0].Seeders != 40 || results[0].Leechers != 3 || results[0].Completed != 90 {
+		t.Errorf("Expected refreshed swarm statistics, got %+v", results)
+	}
+	if results[0].InfoHash != "" {
+		t.Errorf("Expected the re-upload to leave the hash with the original, got %q", results[0].InfoHash)
+	}
+}
+
+func TestTorrentStatsHistory(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	torrents := []models.Torrent{
+		{ID: 8001, Name: "Slow", Magnet: "magnet:a", Category: "Test", Size: "1GB", Date: "2026-01-13", Seeders: 5, Completed: 10},
+		{ID: 8002, Name: "Fast", Magnet: "magnet:b", Category: "Test", Size: "1GB", Date: "2026-01-13", Seeders: 5, Completed: 10},
+	}
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+	if err := dbs.RecordTorrentStats(torrents); err != nil {
+		t.Fatalf("Failed to record stats: %v", err)
+	}
+
+	torrents[0].Completed = 12
+	torrents[1].Seeders = 40
+	torrents[1].Completed = 90
+	if err := dbs.RecordTorrentStats(torrents); err != nil {
+		t.Fatalf("Failed to record stats: %v", err)
+	}
+
+	stats, err := dbs.GetTorrentStats(models.DefaultSite, 8002, 10)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Errorf("Expected 2 snapshots, got %d", len(stats))
+	}
+
+	growth, err := dbs.GetFastestGrowing(time.Hour, 10)
+	if err != nil {
+		t.Fatalf("Failed to get growth: %v", err)
+	}
+	if len(growth) != 2 || growth[0].ID != 8002 {
+		t.Fatalf("Expected 8002 to grow fastest, got %+v", growth)
+	}
+	if growth[0].CompletedDelta != 80 || growth[0].SeedersDelta != 35 {
+		t.Errorf("Expected deltas +35 seeders/+80 completed, got %+d/%+d", growth[0].SeedersDelta, growth[0].CompletedDelta)
+	}
+}
+
+func TestSaveAndGetTorrentDetail(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	torrent := models.Torrent{ID: 9001, Name: "Detail", Magnet: "magnet:d", Category: "Test", Size: "2GB", Date: "2026-01-13", InfoHash: "abc", Trusted: true}
+	if _, err := dbs.InsertTorrents([]models.Torrent{torrent}); err != nil {
+		t.Fatalf("Failed to insert torrent: %v", err)
+	}
+
+	detail := models.TorrentDetail{
+		Torrent:      torrent,
+		Description:  "A batch",
+		Submitter:    "uploader",
+		CommentCount: 3,
+		Files:        []models.TorrentFile{{Path: "a/01.mkv", Size: "1 GiB"}, {Path: "a/02.mkv", Size: "1 GiB"}},
+	}
+	if err := dbs.SaveTorrentDetail(detail); err != nil {
+		t.Fatalf("Failed to save detail: %v", err)
+	}
+
+	// Saving again must replace, not duplicate, the file list
+	detail.Files = detail.Files[:1]
+	if err := dbs.SaveTorrentDetail(detail); err != nil {
+		t.Fatalf("Failed to re-save detail: %v", err)
+	}
+
+	got, err := dbs.GetTorrentDetail(models.DefaultSite, 9001)
+	if err != nil {
+		t.Fatalf("Failed to get detail: %v", err)
+	}
+	if got.Submitter != "uploader" || got.CommentCount != 3 || !got.Torrent.Trusted {
+		t.Errorf("Unexpected detail: %+v", got)
+	}
+	if len(got.Files) != 1 {
+		t.Errorf("Expected 1 file after re-save, got %d", len(got.Files))
+	}
+}
+
+func TestBackfillProgress(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	progress, err := dbs.GetBackfillProgress(models.DefaultSite, "test")
+	if err != nil {
+		t.Fatalf("Failed to get progress: %v", err)
+	}
+	if progress != nil {
+		t.Errorf("Expected no progress for new job, got %+v", progress)
+	}
+
+	want := models.BackfillProgress{Name: "test", StartID: 100, EndID: 1, NextID: 42}
+	if err := dbs.SaveBackfillProgress(want); err != nil {
+		t.Fatalf("Failed to save progress: %v", err)
+	}
+	progress, err = dbs.GetBackfillProgress(models.DefaultSite, "test")
+	if err != nil {
+		t.Fatalf("Failed to get progress: %v", err)
+	}
+	if progress == nil || progress.NextID != 42 || progress.StartID != 100 {
+		t.Errorf("Expected saved progress, got %+v", progress)
+	}
+
+	if err := dbs.MarkTorrentMissing(models.DefaultSite, 43, 404); err != nil {
+		t.Errorf("Failed to mark missing torrent: %v", err)
+	}
+	if err := dbs.MarkTorrentMissing(models.DefaultSite, 43, 404); err != nil {
+		t.Errorf("Failed to re-mark missing torrent: %v", err)
+	}
+}
+
+func TestMarkTorrentDeleted(t *testing.T) {
+	dbs := setupTestDB(t)
+	_ = dbs.DeleteAll()
+
+	torrents := []models.Torrent{
+		{ID: 10001, Name: "Kept", Magnet: "magnet:k", Category: "Test", Size: "1GB", Date: "2026-01-13"},
+		{ID: 10002, Name: "Removed", Magnet: "magnet:r", Category: "Test", Size: "1GB", Date: "2026-01-13"},
+	}
+	if _, err := dbs.InsertTorrents(torrents); err != nil {
+		t.Fatalf("Failed to insert torrents: %v", err)
+	}
+
+	ids, err := dbs.GetTorrentIDsToReconcile(models.DefaultSite, 10)
+	if err != nil {
+		t.Fatalf("Failed to get IDs to reconcile: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 unchecked IDs, got %v", ids)
+	}
+
+	if err := dbs.MarkTorrentChecked(models.DefaultSite, 10001); err != nil {
+		t.Fatalf("Failed to mark checked: %v", err)
+	}
+	if err := dbs.MarkTorrentDeleted(models.DefaultSite, 10002); err != nil {
+		t.Fatalf("Failed to mark deleted: %v", err)
+	}
+
+	visible, err := dbs.FindTorrents(models.TorrentFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to find torrents: %v", err)
+	}
+	if len(visible) != 1 || visible[0].ID != 10001 {
+		t.Errorf("Expected only 10001 visible, got %+v", visible)
+	}
+
+	all, err := dbs.FindTorrents(models.TorrentFilter{IncludeDeleted: true, Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to find torrents: %v", err)
+	}
+	if len(all) != 2 || all[0].DeletedAt == nil {
+		t.Errorf("Expected deleted torrent 10002 flagged first, got %+v", all)
+	}
+
+	latest, err := dbs.GetLatestTorrents(10)
+	if err != nil {
+		t.Fatalf("Failed to get latest torrents: %v", err)
+	}
+	if len(latest) != 1 || latest[0].ID != 10001 {
+		t.Errorf("Expected only 10001 among the latest torrents, got %+v", latest)
+	}
+
+	matches, err := dbs.GetTorrentsByPattern("Removed", 10)
+	if err != nil {
+		t.Fatalf("Failed to get torrents by pattern: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("Expected no match for the removed torrent, got %+v", matches)
+	}
+	count, err := dbs.GetMatchCount("Removed")
+	if err != nil {
+		t.Fatalf("Failed to count matches: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected a match count of 0 for the removed torrent, got %d", count)
+	}
+	total, _, err := dbs.GetTorrentCount()
+	if err != nil {
+		t.Fatalf("Failed to get count: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("Expected a total of 1 without the removed torrent, got %d", total)
+	}
+}
+
 func TestContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Nanosecond)
 	defer cancel()
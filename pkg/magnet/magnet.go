@@ -0,0 +1,166 @@
+// Package magnet parses BitTorrent magnet URIs and normalizes the info hashes
+// they carry, so torrents can be matched regardless of how a link was written.
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotMagnet is returned for URIs that do not use the magnet scheme
+	ErrNotMagnet = errors.New("not a magnet URI")
+	// ErrNoInfoHash is returned for magnet URIs without a BitTorrent info hash
+	ErrNoInfoHash = errors.New("magnet URI has no btih info hash")
+)
+
+// btihPrefix introduces a BitTorrent v1 info hash in an xt parameter
+const btihPrefix = "urn:btih:"
+
+// Magnet is a parsed magnet URI
+type Magnet struct {
+	// InfoHash is the 40-character lower-case hex SHA-1 info hash
+	InfoHash string
+	// Name is the display name (dn), if any
+	Name string
+	// Trackers lists the announce URLs (tr) in the order given
+	Trackers []string
+	// Length is the exact length in bytes (xl), or 0 if not given
+	Length int64
+	// Params holds any other parameters, such as web seeds (ws) or v2 hashes,
+	// so they survive a round trip through String
+	Params url.Values
+}
+
+// Parse parses a magnet URI. The info hash may be hex or base32 encoded, as
+// either form appears in the wild; it is always returned as lower-case hex.
+// The URI must carry one btih topic; other topics, such as v2 btmh hashes,
+// are kept in Params along with any unrecognized parameters.
+func Parse(uri string) (*Magnet, error) {
+	uri = strings.TrimSpace(uri)
+	if len(uri) < len("magnet:") || !strings.EqualFold(uri[:len("magnet:")], "magnet:") {
+		return nil, ErrNotMagnet
+	}
+	query := strings.TrimPrefix(uri[len("magnet:"):], "?")
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid magnet URI: %w", err)
+	}
+
+	m := &Magnet{
+		Name:     values.Get("dn"),
+		Trackers: values["tr"],
+	}
+
+	for key, params := range values {
+		switch key {
+		case "dn", "tr", "xl":
+			continue
+		}
+		// Multiple topics are written as xt.1, xt.2 and so on
+		isTopic := key == "xt" || strings.HasPrefix(key, "xt.")
+		for _, value := range params {
+			if !isTopic || len(value) < len(btihPrefix) || !strings.EqualFold(value[:len(btihPrefix)], btihPrefix) {
+				if m.Params == nil {
+					m.Params = url.Values{}
+				}
+				m.Params.Add(key, value)
+				continue
+			}
+			hash, err := NormalizeInfoHash(value[len(btihPrefix):])
+			if err != nil {
+				return nil, err
+			}
+			if m.InfoHash != "" && m.InfoHash != hash {
+				return nil, fmt.Errorf("magnet URI has conflicting info hashes %s and %s", m.InfoHash, hash)
+			}
+			m.InfoHash = hash
+		}
+	}
+	if m.InfoHash == "" {
+		return nil, ErrNoInfoHash
+	}
+
+	if xl := values.Get("xl"); xl != "" {
+		length, err := strconv.ParseInt(xl, 10, 64)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid magnet length %q", xl)
+		}
+		m.Length = length
+	}
+
+	return m, nil
+}
+
+// String serializes the magnet link with the info hash first, followed by the
+// name, trackers, length and any other parameters
+func (m *Magnet) String() string {
+	var b strings.Builder
+	b.WriteString("magnet:?xt=")
+	b.WriteString(btihPrefix)
+	b.WriteString(m.InfoHash)
+	if m.Name != "" {
+		b.WriteString("&dn=")
+		b.WriteString(url.QueryEscape(m.Name))
+	}
+	for _, tr := range m.Trackers {
+		b.WriteString("&tr=")
+		b.WriteString(url.QueryEscape(tr))
+	}
+	if m.Length > 0 {
+		b.WriteString("&xl=")
+		b.WriteString(strconv.FormatInt(m.Length, 10))
+	}
+
+	keys := make([]string, 0, len(m.Params))
+	for key := range m.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, v := range m.Params[key] {
+			b.WriteString("&")
+			b.WriteString(url.QueryEscape(key))
+			b.WriteString("=")
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+	return b.String()
+}
+
+// NormalizeInfoHash converts a SHA-1 info hash in hex (40 characters) or
+// base32 (32 characters) to lower-case hex
+func NormalizeInfoHash(hash string) (string, error) {
+	hash = strings.TrimSpace(hash)
+	switch len(hash) {
+	case 40:
+		if _, err := hex.DecodeString(hash); err != nil {
+			return "", fmt.Errorf("invalid hex info hash %q", hash)
+		}
+		return strings.ToLower(hash), nil
+	case 32:
+		raw, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err != nil {
+			return "", fmt.Errorf("invalid base32 info hash %q", hash)
+		}
+		return hex.EncodeToString(raw), nil
+	default:
+		return "", fmt.Errorf("info hash %q must be 40 hex or 32 base32 characters", hash)
+	}
+}
+
+// InfoHash returns the normalized info hash of a magnet URI, or an empty
+// string if it has none
+func InfoHash(uri string) string {
+	m, err := Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return m.InfoHash
+}
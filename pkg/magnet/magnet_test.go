@@ -0,0 +1,87 @@
+package magnet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testHash = "abcdef0123456789abcdef0123456789abcdef01"
+
+func TestParse(t *testing.T) {
+	uri := "magnet:?xt=urn:btih:ABCDEF0123456789ABCDEF0123456789ABCDEF01" +
+		"&dn=%5BGroup%5D+Show+-+01+%281080p%29.mkv" +
+		"&tr=http%3A%2F%2Fnyaa.tracker.wf%3A7777%2Fannounce" +
+		"&tr=udp%3A%2F%2Fopen.stealth.si%3A80%2Fannounce" +
+		"&xl=1503238554"
+
+	got, err := Parse(uri)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := &Magnet{
+		InfoHash: testHash,
+		Name:     "[Group] Show - 01 (1080p).mkv",
+		Trackers: []string{"http://nyaa.tracker.wf:7777/announce", "udp://open.stealth.si:80/announce"},
+		Length:   1503238554,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseInfoHashForms(t *testing.T) {
+	for _, uri := range []string{
+		"magnet:?xt=urn:btih:" + testHash,
+		"magnet:?xt=urn:btih:VPG66AJDIVTYTK6N54ASGRLHRGV433YB",
+		"magnet:?xt=urn:btih:vpg66ajdivtytk6n54asgrlhrgv433yb",
+		"MAGNET:?xt=URN:BTIH:" + testHash,
+		"magnet:?xt.1=urn:btmh:1220" + testHash + "&xt.2=urn:btih:" + testHash,
+	} {
+		m, err := Parse(uri)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", uri, err)
+			continue
+		}
+		if m.InfoHash != testHash {
+			t.Errorf("Parse(%q) info hash = %s, want %s", uri, m.InfoHash, testHash)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want error
+	}{
+		{"http://example.com/?xt=urn:btih:" + testHash, ErrNotMagnet},
+		{"", ErrNotMagnet},
+		{"magnet:?dn=name", ErrNoInfoHash},
+		{"magnet:?xt=urn:btmh:1220" + testHash, ErrNoInfoHash},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.uri); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.uri, err, tt.want)
+		}
+	}
+
+	for _, uri := range []string{
+		"magnet:?xt=urn:btih:42",
+		"magnet:?xt=urn:btih:" + testHash[:39] + "g",
+		"magnet:?xt=urn:btih:" + testHash + "&xl=big",
+		"magnet:?xt.1=urn:btih:" + testHash + "&xt.2=urn:btih:0000000000000000000000000000000000000000",
+	} {
+		if _, err := Parse(uri); err == nil {
+			t.Errorf("expected error for %q", uri)
+		}
+	}
+}
+
+func TestInfoHash(t *testing.T) {
+	if got := InfoHash("magnet:?xt=urn:btih:" + testHash + "&dn=x"); got != testHash {
+		t.Errorf("InfoHash = %q, want %q", got, testHash)
+	}
+	if got := InfoHash("magnet:?xt=urn:btih:dup"); got != "" {
+		t.Errorf("expected no info hash for an invalid magnet, got %q", got)
+	}
+}
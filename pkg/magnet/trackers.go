@@ -0,0 +1,105 @@
+package magnet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Rewriter rewrites the tracker list of magnet links
+type Rewriter struct {
+	// Add lists trackers appended to every magnet link
+	Add []string
+	// Strip lists trackers to remove, as announce URLs or bare host names
+	// that match every tracker on that host
+	Strip []string
+	// Dedupe keeps only the first of trackers that differ just in the case
+	// of their scheme or host, or a trailing slash
+	Dedupe bool
+}
+
+// Rewrite parses a magnet link, rewrites its trackers and serializes it again
+func (r Rewriter) Rewrite(uri string) (string, error) {
+	m, err := Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	r.Apply(m)
+	return m.String(), nil
+}
+
+// Apply rewrites the trackers of m in place. The magnet's own trackers keep
+// their order and come before the added ones; stripped trackers are removed
+// from both.
+func (r Rewriter) Apply(m *Magnet) {
+	seen := make(map[string]bool)
+	trackers := make([]string, 0, len(m.Trackers)+len(r.Add))
+	for _, list := range [][]string{m.Trackers, r.Add} {
+		for _, tr := range list {
+			tr = strings.TrimSpace(tr)
+			if tr == "" || r.stripped(tr) {
+				continue
+			}
+			if r.Dedupe {
+				key := normalizeTracker(tr)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			trackers = append(trackers, tr)
+		}
+	}
+	m.Trackers = trackers
+}
+
+// stripped reports whether tracker matches an entry of Strip
+func (r Rewriter) stripped(tracker string) bool {
+	for _, s := range r.Strip {
+		s = strings.TrimSpace(s)
+		if strings.Contains(s, "://") {
+			if normalizeTracker(s) == normalizeTracker(tracker) {
+				return true
+			}
+		} else if u, err := url.Parse(tracker); err == nil && strings.EqualFold(u.Hostname(), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTracker returns the form of an announce URL used to compare trackers
+func normalizeTracker(tracker string) string {
+	u, err := url.Parse(tracker)
+	if err != nil || u.Host == "" {
+		return tracker
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// ReadTrackers reads a tracker list with one announce URL per line, in the
+// format of common public tracker lists. Blank lines and lines starting with
+// # are skipped.
+func ReadTrackers(r io.Reader) ([]string, error) {
+	var trackers []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		tr := strings.TrimSpace(scanner.Text())
+		if tr == "" || strings.HasPrefix(tr, "#") {
+			continue
+		}
+		if u, err := url.Parse(tr); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("line %d: invalid tracker %q", line, tr)
+		}
+		trackers = append(trackers, tr)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return trackers, nil
+}
@@ -0,0 +1,89 @@
+package magnet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewriterApply(t *testing.T) {
+	m := &Magnet{
+		InfoHash: testHash,
+		Trackers: []string{
+			"http://nyaa.tracker.wf:7777/announce",
+			"udp://dead.example.com:80/announce",
+			"udp://Tracker.Example.org:1337/announce/",
+			"udp://gone.example.net:6969/announce",
+		},
+	}
+	Rewriter{
+		Add: []string{
+			"udp://tracker.example.org:1337/announce",
+			"udp://open.stealth.si:80/announce",
+			" ",
+		},
+		Strip:  []string{"DEAD.example.com", "udp://gone.example.net:6969/announce"},
+		Dedupe: true,
+	}.Apply(m)
+
+	want := []string{
+		"http://nyaa.tracker.wf:7777/announce",
+		"udp://Tracker.Example.org:1337/announce/",
+		"udp://open.stealth.si:80/announce",
+	}
+	if !reflect.DeepEqual(m.Trackers, want) {
+		t.Errorf("Trackers = %v, want %v", m.Trackers, want)
+	}
+}
+
+func TestRewriterKeepsDuplicatesWithoutDedupe(t *testing.T) {
+	m := &Magnet{InfoHash: testHash, Trackers: []string{"udp://a.example:1/announce"}}
+	Rewriter{Add: []string{"udp://a.example:1/announce"}}.Apply(m)
+	if len(m.Trackers) != 2 {
+		t.Errorf("expected both trackers to be kept, got %v", m.Trackers)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	m := &Magnet{
+		InfoHash: testHash,
+		Name:     "[Group] Show & Friends - 01 (1080p).mkv",
+		Trackers: []string{"http://nyaa.tracker.wf:7777/announce", "udp://open.stealth.si:80/announce?key=a&b"},
+		Length:   1503238554,
+		Params: map[string][]string{
+			"ws":   {"https://example.com/Show%2001.mkv"},
+			"xt.1": {"urn:btmh:1220" + testHash},
+		},
+	}
+
+	parsed, err := Parse(m.String())
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", m.String(), err)
+	}
+	if !reflect.DeepEqual(parsed, m) {
+		t.Errorf("round trip = %+v, want %+v", parsed, m)
+	}
+	if !strings.HasPrefix(m.String(), "magnet:?xt=urn:btih:"+testHash+"&dn=") {
+		t.Errorf("expected the info hash and name first, got %q", m.String())
+	}
+}
+
+func TestReadTrackers(t *testing.T) {
+	list := `# public trackers
+udp://tracker.opentrackr.org:1337/announce
+
+  http://nyaa.tracker.wf:7777/announce
+`
+	trackers, err := ReadTrackers(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("ReadTrackers failed: %v", err)
+	}
+	want := []string{"udp://tracker.opentrackr.org:1337/announce", "http://nyaa.tracker.wf:7777/announce"}
+	if !reflect.DeepEqual(trackers, want) {
+		t.Errorf("ReadTrackers = %v, want %v", trackers, want)
+	}
+
+	if _, err := ReadTrackers(strings.NewReader("udp://ok.example:1/announce\nnot a tracker\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error for line 2, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// SortField represents a whitelisted column torrents can be ordered by
+type SortField string
+
+const (
+	// SortByID orders torrents newest first
+	SortByID SortField = "id"
+	// SortBySeeders orders torrents by current seeder count
+	SortBySeeders SortField = "seeders"
+	// SortByLeechers orders torrents by current leecher count
+	SortByLeechers SortField = "leechers"
+	// SortByCompleted orders torrents by completed download count
+	SortByCompleted SortField = "completed"
+	// SortBySize orders torrents largest first
+	SortBySize SortField = "size"
+	// SortByDate orders torrents by upload time, most recent first
+	SortByDate SortField = "date"
+)
+
+// TorrentFilter describes which torrents to query and how to order them
+type TorrentFilter struct {
+	// Site restricts results to one site when non-empty
+	Site string
+	// Pattern matches torrent names using the LIKE operator when non-empty
+	Pattern string
+	// MinSeeders excludes torrents with fewer seeders
+	MinSeeders int
+	// MinSize and MaxSize bound the size in bytes when positive
+	MinSize int64
+	MaxSize int64
+	// PublishedAfter and PublishedBefore bound the upload time when non-zero
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+	// SortBy selects the descending sort column, defaulting to SortByID
+	SortBy SortField
+	// IncludeDeleted also returns torrents that were removed from Nyaa
+	IncludeDeleted bool
+	Limit          int
+}
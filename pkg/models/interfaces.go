@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // PushTarget represents the download target for push status updates
 type PushTarget string
 
@@ -12,7 +14,8 @@ const (
 
 // TorrentWriter defines the interface for writing torrent data
 type TorrentWriter interface {
-	InsertTorrents(torrents []Torrent) error
+	// InsertTorrents stores torrents and returns how many were newly inserted
+	InsertTorrents(torrents []Torrent) (int, error)
 }
 
 // TorrentReader defines the interface for reading torrent data
@@ -22,17 +25,61 @@ type TorrentReader interface {
 	GetLatestTorrents(limit int) ([]Torrent, error)
 	GetTorrentCount() (total, withMagnet int, err error)
 	GetMatchCount(pattern string) (int, error)
+	GetMaxTorrentID(site string) (int, error)
+	FindTorrents(filter TorrentFilter) ([]Torrent, error)
+	GetTorrentByInfoHash(infoHash string) (*Torrent, error)
+}
+
+// TorrentStatsWriter defines the interface for recording swarm history
+type TorrentStatsWriter interface {
+	RecordTorrentStats(torrents []Torrent) error
+}
+
+// TorrentStatsReader defines the interface for reading swarm history
+type TorrentStatsReader interface {
+	GetTorrentStats(site string, id int, limit int) ([]TorrentStats, error)
+	GetFastestGrowing(window time.Duration, limit int) ([]TorrentGrowth, error)
+}
+
+// TorrentDetailWriter defines the interface for storing detail page data
+type TorrentDetailWriter interface {
+	SaveTorrentDetail(detail TorrentDetail) error
+}
+
+// TorrentDetailReader defines the interface for reading detail page data
+type TorrentDetailReader interface {
+	GetTorrentDetail(site string, id int) (*TorrentDetail, error)
+}
+
+// BackfillStore defines the interface for persisting backfill progress
+type BackfillStore interface {
+	GetBackfillProgress(site, name string) (*BackfillProgress, error)
+	SaveBackfillProgress(progress BackfillProgress) error
+	MarkTorrentMissing(site string, id int, statusCode int) error
+}
+
+// TorrentReconciler defines the interface for tracking torrents removed from Nyaa
+type TorrentReconciler interface {
+	GetTorrentIDsToReconcile(site string, limit int) ([]int, error)
+	MarkTorrentDeleted(site string, id int) error
+	MarkTorrentChecked(site string, id int) error
 }
 
 // TorrentStatusUpdater defines the interface for updating torrent push status
 type TorrentStatusUpdater interface {
-	UpdatePushedStatus(id int, target PushTarget) error
+	UpdatePushedStatus(site string, id int, target PushTarget) error
 }
 
 // DBService combines all database interfaces for convenience
 type DBService interface {
 	TorrentWriter
 	TorrentReader
+	TorrentStatsWriter
+	TorrentStatsReader
+	TorrentDetailWriter
+	TorrentDetailReader
+	BackfillStore
+	TorrentReconciler
 	TorrentStatusUpdater
 	Close()
 }
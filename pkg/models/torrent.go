@@ -1,13 +1,77 @@
 package models
 
+import "time"
+
+// DefaultSite is the site torrents belong to when none is given
+const DefaultSite = "nyaa"
+
 // Torrent represents a torrent entry from Nyaa
 type Torrent struct {
+	// Site names the tracker the ID belongs to, such as "nyaa" or "sukebei"
+	Site                 string
 	ID                   int
 	Name                 string
 	Magnet               string
 	Category             string
 	Size                 string
 	Date                 string
+	Seeders              int
+	Leechers             int
+	Completed            int
+	InfoHash             string
+	CategoryID           string
+	Trusted              bool
+	Remake               bool
 	PushedToTransmission bool
 	PushedToAria2        bool
+	// SizeBytes is Size parsed into bytes, or 0 if it could not be parsed
+	SizeBytes int64
+	// PublishedAt is when the torrent was uploaded, or zero if unknown
+	PublishedAt time.Time
+	// DeletedAt is set once reconciliation finds the torrent removed from Nyaa
+	DeletedAt *time.Time
+}
+
+// TorrentStats is a point-in-time snapshot of a torrent's swarm health
+type TorrentStats struct {
+	Site       string
+	TorrentID  int
+	Seeders    int
+	Leechers   int
+	Completed  int
+	RecordedAt time.Time
+}
+
+// TorrentGrowth describes how a torrent's swarm changed over a time window
+type TorrentGrowth struct {
+	Torrent
+	SeedersDelta   int
+	CompletedDelta int
+}
+
+// TorrentDetail holds the information only shown on a torrent's /view/ID page
+type TorrentDetail struct {
+	Torrent        Torrent
+	Description    string
+	Submitter      string
+	InformationURL string
+	CommentCount   int
+	Files          []TorrentFile
+}
+
+// TorrentFile is a single entry in a torrent's file list
+type TorrentFile struct {
+	Path string
+	Size string
+}
+
+// BackfillProgress records how far a resumable backfill job has advanced.
+// Jobs walk IDs downwards from StartID to EndID inclusive.
+type BackfillProgress struct {
+	Site      string
+	Name      string
+	StartID   int
+	EndID     int
+	NextID    int
+	UpdatedAt time.Time
 }